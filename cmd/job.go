@@ -4,7 +4,6 @@ Copyright © 2024 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
-	"encoding/json"
 	"errors"
 	"io/fs"
 	"log/slog"
@@ -21,28 +20,45 @@ var jobCmd = &cobra.Command{
 	Long: `Placeholder, will get proper name and arguments later.
 
 Add empty job file with default (nil) values.
-The file will be job.json`,
+The file will be job.json, or job.yaml with --format yaml.`,
 	Run: createEmptyJob,
 }
 
+var (
+	jobFormatFlag *string
+	jobOutputFlag *string
+)
+
 func createEmptyJob(cmd *cobra.Command, args []string) {
+	format, err := silence.ParseConfigFormat(*jobFormatFlag)
+	if err != nil {
+		slog.Error("invalid format", slog.String(silence.ErrorKey, err.Error()))
+		os.Exit(1)
+	}
+
+	path := *jobOutputFlag
+	if path == "" {
+		path = "job." + string(format)
+	}
+
 	job := silence.DefaultJob("")
-	data, err := json.MarshalIndent(job, "", "  ")
+	data, err := silence.MarshalJob(job, format)
 	if err != nil {
-		slog.Error("cannot marshal json", slog.String(silence.ErrorKey, err.Error()))
+		slog.Error("cannot marshal job", slog.String(silence.ErrorKey, err.Error()))
 		os.Exit(1)
 	}
 
-	_, err = os.Stat(silence.DefaultJobConfigPath)
+	_, err = os.Stat(path)
 	if err == nil {
-		slog.Error("file alredy exists")
+		slog.Error("file alredy exists", slog.String("file", path))
+		os.Exit(1)
 	}
 	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		slog.Error("error when stating file", slog.String(silence.ErrorKey, err.Error()))
 		os.Exit(1)
 	}
 
-	err = os.WriteFile(silence.DefaultJobConfigPath, data, 0744)
+	err = os.WriteFile(path, data, 0744)
 	if err != nil {
 		slog.Error("error when writing file", slog.String(silence.ErrorKey, err.Error()))
 	}
@@ -50,4 +66,7 @@ func createEmptyJob(cmd *cobra.Command, args []string) {
 
 func init() {
 	rootCmd.AddCommand(jobCmd)
+
+	jobFormatFlag = jobCmd.Flags().StringP("format", "f", string(silence.FormatJSON), "Format of the job file, json or yaml")
+	jobOutputFlag = jobCmd.Flags().StringP("output", "o", "", "Path of the job file (default is job.json or job.yaml)")
 }
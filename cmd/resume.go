@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"silence/silence"
+
+	"github.com/spf13/cobra"
+)
+
+// resumeCmd represents the resume command
+var resumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume interrupted series of crawls.",
+	Long: `Resume interrupted series of crawls.
+
+Loads the queue of crawls saved in the working directory by previous run
+and continues from the first unfinished part.`,
+	Run: resumeApp,
+}
+
+var appResume = new(silence.App)
+
+func resumeApp(cmd *cobra.Command, args []string) {
+	silence.Resume(appResume.InitCommand(cmd, args))
+}
+
+func init() {
+	rootCmd.AddCommand(resumeCmd)
+
+	addAppFlags(resumeCmd, appResume)
+}
@@ -23,6 +23,16 @@ func runApp(cmd *cobra.Command, args []string) {
 func init() {
 	rootCmd.AddCommand(runCmd)
 
-	app.WorkDirFlag = runCmd.Flags().String("work-dir", "", "Sets working directory")
-	app.DebugFLag = runCmd.Flags().BoolP("debug", "d", false, "Sets loging level to DEBUG")
+	addAppFlags(runCmd, app)
+	app.DryRunFlag = runCmd.Flags().Bool("dry-run", false, "Splits seeds and renders crawler-beans of every crawl without contacting Heritrix")
+}
+
+// Flags shared by all commands that run crawls
+func addAppFlags(cmd *cobra.Command, app *silence.App) {
+	app.WorkDirFlag = cmd.Flags().String("work-dir", "", "Sets working directory")
+	app.DebugFLag = cmd.Flags().BoolP("debug", "d", false, "Sets loging level to DEBUG")
+	app.LockFileFlag = cmd.Flags().String("lock-file", "", "Sets path to lock file (default is silence.lock in temp directory)")
+	app.MetricsListenFlag = cmd.Flags().String("metrics-listen", "", "Serves Prometheus metrics on /metrics at this address, e.g. :9464")
+	app.ForceFlag = cmd.Flags().Bool("force", false, "Starts crawls even when Heritrix job has unfinished crawl")
+	app.ConfigFlag = cmd.Flags().StringP("config", "c", "", "Sets path to job config, json or yaml (default is job.json, job.yaml or job.yml in working directory)")
 }
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"silence/silence"
+
+	"github.com/spf13/cobra"
+)
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check job configuration.",
+	Long: `Check job configuration.
+
+Reports all problems in the job config at once, Heritrix is not contacted.`,
+	Run: validateApp,
+}
+
+var appValidate = new(silence.App)
+
+func validateApp(cmd *cobra.Command, args []string) {
+	silence.Validate(appValidate.InitCommand(cmd, args))
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	appValidate.WorkDirFlag = validateCmd.Flags().String("work-dir", "", "Sets working directory")
+	appValidate.DebugFLag = validateCmd.Flags().BoolP("debug", "d", false, "Sets loging level to DEBUG")
+	appValidate.ConfigFlag = validateCmd.Flags().StringP("config", "c", "", "Sets path to job config, json or yaml (default is job.json, job.yaml or job.yml in working directory)")
+}
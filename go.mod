@@ -5,9 +5,12 @@ go 1.23.0
 require (
 	github.com/icholy/digest v0.1.23
 	github.com/spf13/cobra v1.8.1
+	golang.org/x/net v0.40.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
+	golang.org/x/text v0.25.0 // indirect
 )
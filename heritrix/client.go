@@ -0,0 +1,262 @@
+// Package heritrix implements client for the REST API of Heritrix 3 crawler.
+//
+// Heritrix answers with XML when the request has application/xml in Accept
+// header, the responses are parsed into Engine and Job structures.
+// All actions are send as url encoded forms, the same way the web UI does it.
+package heritrix
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/icholy/digest"
+)
+
+const (
+	AcceptHeaderKey = "Accept"
+	AcceptXML       = "application/xml"
+)
+
+const ActionKey = "action"
+
+const EnginePath = "/engine"
+
+// Error returned when Heritrix answers with other status code than 200.
+type StatusError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Status     string
+}
+
+func (err *StatusError) Error() string {
+	return fmt.Sprintf("%s %s returned non ok status code (%s)", err.Method, err.Path, err.Status)
+}
+
+type Client struct {
+	address *url.URL
+	http    *http.Client
+	retry   RetryPolicy
+}
+
+// Creates client for Heritrix listening on address. Address without
+// scheme is treated as https, which is what Heritrix uses by default.
+func NewClient(address, username, password string) (*Client, error) {
+	// Heritrix uses self signed certificates, this is fine for now
+	heritrixTransport := http.DefaultTransport.(*http.Transport).Clone()
+	heritrixTransport.TLSClientConfig.InsecureSkipVerify = true
+	digestTransport := &digest.Transport{
+		Username:  username,
+		Password:  password,
+		Transport: heritrixTransport,
+	}
+
+	return NewClientWithHTTP(address, &http.Client{Transport: digestTransport})
+}
+
+// Creates client that uses provided http.Client, which must handle
+// authentication by itself.
+func NewClientWithHTTP(address string, httpClient *http.Client) (*Client, error) {
+	if !strings.HasPrefix(address, "http://") && !strings.HasPrefix(address, "https://") {
+		address = "https://" + address
+	}
+
+	parsed, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Host == "" {
+		return nil, fmt.Errorf("address %s has no host", address)
+	}
+
+	return &Client{address: parsed, http: httpClient}, nil
+}
+
+// Address of Heritrix this client talks to.
+func (client *Client) Address() string {
+	return client.address.String()
+}
+
+// Sets policy used for all requests, by default requests are not retried.
+func (client *Client) SetRetryPolicy(policy RetryPolicy) {
+	client.retry = policy
+}
+
+// Returns state of the engine.
+func (client *Client) Engine(ctx context.Context) (*Engine, error) {
+	engine := new(Engine)
+	err := client.get(ctx, EnginePath, engine)
+	if err != nil {
+		return nil, err
+	}
+	return engine, nil
+}
+
+// Returns state of the job.
+func (client *Client) Job(ctx context.Context, name string) (*Job, error) {
+	job := new(Job)
+	err := client.get(ctx, JobPath(name), job)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Rescans jobs directory for new jobs.
+func (client *Client) Rescan(ctx context.Context) error {
+	return client.EngineAction(ctx, "rescan", nil)
+}
+
+// Adds directory outside of jobs directory as a job.
+func (client *Client) AddJobDir(ctx context.Context, path string) error {
+	return client.EngineAction(ctx, "add", url.Values{"addpath": {path}})
+}
+
+// Creates new job with default profile in the jobs directory of Heritrix.
+func (client *Client) CreateJob(ctx context.Context, name string) error {
+	return client.EngineAction(ctx, "create", url.Values{"createpath": {name}})
+}
+
+// Copies configuration of job to new job, optionally as a profile.
+func (client *Client) CopyJob(ctx context.Context, name, newName string, asProfile bool) error {
+	values := url.Values{"copyTo": {newName}}
+	if asProfile {
+		values.Set("asProfile", "on")
+	}
+	return client.post(ctx, JobPath(name), values)
+}
+
+// Asks the engine to exit the java process. Heritrix refuses to exit
+// while some job is running.
+func (client *Client) Exit(ctx context.Context) error {
+	return client.EngineAction(ctx, "exit java process", url.Values{"im_sure": {"on"}})
+}
+
+func (client *Client) Build(ctx context.Context, name string) error {
+	return client.JobAction(ctx, name, "build")
+}
+
+func (client *Client) Launch(ctx context.Context, name string) error {
+	return client.JobAction(ctx, name, "launch")
+}
+
+func (client *Client) Pause(ctx context.Context, name string) error {
+	return client.JobAction(ctx, name, "pause")
+}
+
+func (client *Client) Unpause(ctx context.Context, name string) error {
+	return client.JobAction(ctx, name, "unpause")
+}
+
+func (client *Client) Checkpoint(ctx context.Context, name string) error {
+	return client.JobAction(ctx, name, "checkpoint")
+}
+
+func (client *Client) Terminate(ctx context.Context, name string) error {
+	return client.JobAction(ctx, name, "terminate")
+}
+
+func (client *Client) Teardown(ctx context.Context, name string) error {
+	return client.JobAction(ctx, name, "teardown")
+}
+
+// Sends action to the engine, values are added to the form.
+func (client *Client) EngineAction(ctx context.Context, action string, values url.Values) error {
+	if values == nil {
+		values = url.Values{}
+	}
+	values.Set(ActionKey, action)
+	return client.post(ctx, EnginePath, values)
+}
+
+// Sends action to the job.
+func (client *Client) JobAction(ctx context.Context, name string, action string) error {
+	return client.post(ctx, JobPath(name), url.Values{ActionKey: {action}})
+}
+
+// Path of the job resource relative to the Heritrix address.
+func JobPath(name string) string {
+	return EnginePath + "/job/" + name
+}
+
+func (client *Client) get(ctx context.Context, path string, v any) error {
+	var body []byte
+	err := client.retry.do(ctx, http.MethodGet, path, func() error {
+		response, err := client.do(ctx, http.MethodGet, path, nil)
+		if err != nil {
+			return err
+		}
+		defer response.Body.Close()
+
+		body, err = io.ReadAll(response.Body)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	err = xml.Unmarshal(body, v)
+	if err != nil {
+		return fmt.Errorf("failed to parse response from %s: %w", path, err)
+	}
+	return nil
+}
+
+func (client *Client) post(ctx context.Context, path string, values url.Values) error {
+	return client.retry.do(ctx, http.MethodPost, path, func() error {
+		response, err := client.do(ctx, http.MethodPost, path, values)
+		if err != nil {
+			return err
+		}
+		defer response.Body.Close()
+
+		// Drain the body so the connection can be reused
+		_, err = io.Copy(io.Discard, response.Body)
+		return err
+	})
+}
+
+// Sends the request and checks the status code. Body of returned response must be closed.
+func (client *Client) do(ctx context.Context, method string, path string, values url.Values) (*http.Response, error) {
+	address := *client.address
+	address.Path = strings.TrimSuffix(address.Path, "/") + path
+
+	var body io.Reader
+	if method == http.MethodGet || values == nil {
+		body = http.NoBody
+	} else {
+		body = strings.NewReader(values.Encode())
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, address.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set(AcceptHeaderKey, AcceptXML)
+	if body != http.NoBody {
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	response, err := client.http.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, &StatusError{
+			Method:     method,
+			Path:       path,
+			StatusCode: response.StatusCode,
+			Status:     response.Status,
+		}
+	}
+
+	return response, nil
+}
@@ -0,0 +1,231 @@
+package heritrix_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"silence/heritrix"
+	"silence/heritrix/heritrixtest"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientJobLifecycle(t *testing.T) {
+	server := heritrixtest.NewServer("admin", "secret")
+	defer server.Close()
+	server.AddJob("topics")
+
+	client, err := heritrix.NewClient(server.URL, "admin", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	engine, err := client.Engine(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if engine.FindJob("topics") == nil {
+		t.Fatalf("job topics not listed by engine: %+v", engine.Jobs)
+	}
+
+	err = client.Build(ctx, "topics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Build completes in background, unbuilt job is launchable as well
+	var descriptions []string
+	for i := 0; i < 2; i++ {
+		job, err := client.Job(ctx, "topics")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !job.IsLaunchable {
+			t.Fatalf("job is not launchable: %+v", job)
+		}
+		descriptions = append(descriptions, job.StatusDescription)
+	}
+	if !slices.Equal(descriptions, []string{"Unbuilt", "Ready"}) {
+		t.Fatalf("got descriptions %v during build", descriptions)
+	}
+	err = client.Launch(ctx, "topics")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var states []string
+	for i := 0; i < 3; i++ {
+		job, err := client.Job(ctx, "topics")
+		if err != nil {
+			t.Fatal(err)
+		}
+		states = append(states, job.ControllerState)
+		if job.ControllerState == heritrix.StatePaused {
+			if !job.HasAction("unpause") {
+				t.Fatalf("paused job cannot be unpaused: %v", job.Actions)
+			}
+			err = client.Unpause(ctx, "topics")
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	expected := []string{heritrix.StatePreparing, heritrix.StatePaused, heritrix.StateRunning}
+	if !slices.Equal(states, expected) {
+		t.Fatalf("got states %v, expected %v", states, expected)
+	}
+
+	for _, action := range []func(context.Context, string) error{client.Terminate, client.Teardown} {
+		err = action(ctx, "topics")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	job, err := client.Job(ctx, "topics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.IsRunning || !job.IsLaunchable {
+		t.Fatalf("job was not torn down: %+v", job)
+	}
+
+	actions := server.Actions("topics")
+	expected = []string{"build", "launch", "unpause", "terminate", "teardown"}
+	if !slices.Equal(actions, expected) {
+		t.Fatalf("got actions %v, expected %v", actions, expected)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	server := heritrixtest.NewServer("admin", "secret")
+	defer server.Close()
+	server.AddJob("topics")
+	ctx := context.Background()
+
+	client, err := heritrix.NewClient(server.URL, "admin", "wrong")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Engine(ctx)
+	var statusErr *heritrix.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected unauthorized, got %v", err)
+	}
+
+	client, err = heritrix.NewClient(server.URL, "admin", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Job(ctx, "missing")
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	server.Fail(http.MethodPost, heritrix.JobPath("topics"), http.StatusInternalServerError, 1)
+	err = client.Build(ctx, "topics")
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected injected failure, got %v", err)
+	}
+	err = client.Build(ctx, "topics")
+	if err != nil {
+		t.Fatalf("failure should be injected only once: %v", err)
+	}
+}
+
+func TestClientRetry(t *testing.T) {
+	server := heritrixtest.NewServer("admin", "secret")
+	defer server.Close()
+	server.AddJob("topics")
+
+	client, err := heritrix.NewClient(server.URL, "admin", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	retries := 0
+	client.SetRetryPolicy(heritrix.RetryPolicy{
+		Attempts:     3,
+		InitialDelay: time.Millisecond,
+		Multiplier:   2,
+		Jitter:       0.5,
+		StatusCodes:  []int{http.StatusServiceUnavailable},
+		OnRetry: func(method, path string, attempt int, err error, delay time.Duration) {
+			retries++
+		},
+	})
+	ctx := context.Background()
+
+	server.Fail(http.MethodGet, heritrix.JobPath("topics"), http.StatusServiceUnavailable, 2)
+	_, err = client.Job(ctx, "topics")
+	if err != nil || retries != 2 {
+		t.Fatalf("expected success after 2 retries, got %d retries and %v", retries, err)
+	}
+
+	server.Fail(http.MethodPost, heritrix.JobPath("topics"), http.StatusServiceUnavailable, 3)
+	err = client.Build(ctx, "topics")
+	var retryErr *heritrix.RetryError
+	if !errors.As(err, &retryErr) || retryErr.Attempts != 3 {
+		t.Fatalf("expected retries to run out, got %v", err)
+	}
+
+	server.Fail(http.MethodPost, heritrix.JobPath("topics"), http.StatusInternalServerError, 1)
+	err = client.Build(ctx, "topics")
+	var statusErr *heritrix.StatusError
+	if errors.As(err, &retryErr) || !errors.As(err, &statusErr) {
+		t.Fatalf("status code that is not retriable must fail at once, got %v", err)
+	}
+
+	server.Close()
+	retries = 0
+	_, err = client.Engine(ctx)
+	if err == nil || retries != 0 {
+		t.Fatalf("network errors must not be retried when disabled, got %d retries and %v", retries, err)
+	}
+}
+
+func TestClientRetryNetworkErrors(t *testing.T) {
+	// Connection is closed after the request was received, as when
+	// Heritrix did the action but the response was lost
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	defer server.Close()
+
+	client, err := heritrix.NewClient(server.URL, "admin", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetRetryPolicy(heritrix.RetryPolicy{
+		Attempts:      3,
+		InitialDelay:  time.Millisecond,
+		NetworkErrors: true,
+	})
+	ctx := context.Background()
+
+	_, err = client.Job(ctx, "topics")
+	if err == nil || requests.Load() != 3 {
+		t.Fatalf("status check must be sent 3 times, got %d requests and %v", requests.Load(), err)
+	}
+
+	requests.Store(0)
+	err = client.Launch(ctx, "topics")
+	if err == nil || requests.Load() != 1 {
+		t.Fatalf("action that reached heritrix must not be sent again, got %d requests and %v", requests.Load(), err)
+	}
+
+	// Refused connection means the action was never sent
+	server.Close()
+	err = client.Launch(ctx, "topics")
+	var retryErr *heritrix.RetryError
+	if !errors.As(err, &retryErr) || retryErr.Attempts != 3 {
+		t.Fatalf("refused action must be retried, got %v", err)
+	}
+}
@@ -0,0 +1,267 @@
+package heritrixtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"silence/heritrix"
+)
+
+// Logs written by Heritrix into logs directory of each launch.
+var LogFiles = []string{
+	"crawl.log",
+	"progress-statistics.log",
+	"uri-errors.log",
+	"runtime-errors.log",
+	"nonfatal-errors.log",
+	"alerts.log",
+}
+
+// Job of the fake engine, it must be only accessed while holding the lock
+// of the server, methods of Server take care of that.
+type Job struct {
+	Name string
+
+	original []string
+	script   []string
+	step     int
+	built    bool
+	// Build was requested, it completes with the next status check
+	building    bool
+	launched    bool
+	launchCount int
+	exitStatus  string
+
+	// Unpause received before the job reached PAUSED
+	unpaused bool
+	// Number of status checks while running, drives reported progress
+	progress int64
+	// Teardown is accepted but the job keeps running
+	stuckTeardown bool
+
+	// Job directory, empty when server has no JobsDir
+	dir       string
+	launchDir string
+	// Content of crawler-beans read by each build, nil when it was missing
+	builtConfigs [][]byte
+}
+
+// Current controller state, empty when the job was not built and NASCENT
+// when it was built but not launched.
+func (job *Job) state() string {
+	if !job.launched {
+		if job.built {
+			return heritrix.StateNascent
+		}
+		return ""
+	}
+	return job.script[job.step]
+}
+
+// Moves the job to the next state of the script.
+func (job *Job) advance() {
+	if job.building {
+		job.building = false
+		job.built = true
+	}
+	if !job.launched {
+		return
+	}
+	if job.state() == heritrix.StatePaused {
+		if !job.unpaused {
+			return
+		}
+		job.unpaused = false
+	}
+	if job.state() == heritrix.StateRunning {
+		job.progress++
+	}
+	if job.step < len(job.script)-1 {
+		job.step++
+	}
+	if job.state() == heritrix.StateFinished && job.exitStatus == "" {
+		job.exitStatus = "FINISHED"
+	}
+}
+
+func (job *Job) do(action string) error {
+	switch action {
+	case "build":
+		if !job.built {
+			job.building = true
+		}
+		job.readConfig()
+	case "launch":
+		if job.launched {
+			return nil
+		}
+		if job.building {
+			return fmt.Errorf("job %s is still being built", job.Name)
+		}
+		err := job.writeLogs()
+		if err != nil {
+			return err
+		}
+		job.built = true
+		job.launched = true
+		job.launchCount++
+		job.script = job.original
+		job.step = 0
+		job.exitStatus = ""
+		job.unpaused = false
+		job.progress = 0
+	case "unpause":
+		if job.state() == heritrix.StatePaused && job.step < len(job.script)-1 {
+			job.step++
+		} else if job.launched {
+			job.unpaused = true
+		}
+	case "pause":
+		// Not scripted, pausing is never used by silence
+	case "terminate":
+		if job.launched && job.state() != heritrix.StateFinished {
+			job.script = append(job.script[:job.step:job.step], heritrix.StateFinished)
+			job.exitStatus = "ABORTED"
+		}
+	case "teardown":
+		if job.stuckTeardown {
+			return nil
+		}
+		job.built = false
+		job.building = false
+		job.launched = false
+		job.step = 0
+	}
+	return nil
+}
+
+// Records crawler-beans from the job directory, as Heritrix reads them
+// on build.
+func (job *Job) readConfig() {
+	if job.dir == "" {
+		return
+	}
+	config, err := os.ReadFile(filepath.Join(job.dir, "crawler-beans.cxml"))
+	if err != nil {
+		config = nil
+	}
+	job.builtConfigs = append(job.builtConfigs, config)
+}
+
+// Creates launch directory with logs and points latest to it.
+func (job *Job) writeLogs() error {
+	if job.dir == "" {
+		return nil
+	}
+
+	launchDir := filepath.Join(job.dir, fmt.Sprintf("2024010100%04d", job.launchCount+1))
+	logsDir := filepath.Join(launchDir, "logs")
+	err := os.MkdirAll(logsDir, 0755)
+	if err != nil {
+		return err
+	}
+	for _, name := range LogFiles {
+		content := fmt.Sprintf("%s of %s launch %d\n", name, job.Name, job.launchCount+1)
+		err = os.WriteFile(filepath.Join(logsDir, name), []byte(content), 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	latest := filepath.Join(job.dir, "latest")
+	os.Remove(latest)
+	err = os.Symlink(filepath.Base(launchDir), latest)
+	if err != nil {
+		return err
+	}
+	job.launchDir = launchDir
+	return nil
+}
+
+func (job *Job) actions() []string {
+	switch job.state() {
+	case "":
+		return []string{"build", "launch"}
+	case heritrix.StateNascent:
+		return []string{"launch", "teardown"}
+	case heritrix.StatePaused:
+		return []string{"unpause", "checkpoint", "terminate"}
+	case heritrix.StateFinished:
+		return []string{"teardown"}
+	default:
+		return []string{"pause", "checkpoint", "terminate"}
+	}
+}
+
+func (job *Job) status() *heritrix.Job {
+	status := &heritrix.Job{
+		ShortName:         job.Name,
+		ControllerState:   job.state(),
+		ExitStatus:        job.exitStatus,
+		StatusDescription: job.description(),
+		Actions:           job.actions(),
+		LaunchCount:       job.launchCount,
+		IsRunning:         job.launched,
+		IsLaunchable:      !job.launched,
+	}
+	if job.dir != "" {
+		status.PrimaryConfig = filepath.Join(job.dir, "crawler-beans.cxml")
+	}
+	if job.launched && job.launchDir != "" {
+		status.CrawlLogFilePath = filepath.Join(job.launchDir, "logs", "crawl.log")
+	}
+	if job.launched {
+		job.addReports(status)
+	}
+	return status
+}
+
+// Fills progress reports, every status check while running downloads
+// ten more URIs.
+func (job *Job) addReports(status *heritrix.Job) {
+	downloaded := 10 * job.progress
+	queued := int64(0)
+	if job.state() != heritrix.StateFinished {
+		queued = 100
+	}
+	busy := 0
+	if job.state() == heritrix.StateRunning {
+		busy = 2
+	}
+	status.URITotals = &heritrix.URITotalsReport{
+		DownloadedURICount: downloaded,
+		QueuedURICount:     queued,
+		TotalURICount:      downloaded + queued,
+	}
+	status.SizeTotals = &heritrix.SizeTotalsReport{
+		Total:      1024 * downloaded,
+		TotalCount: downloaded,
+		Novel:      1024 * downloaded,
+		NovelCount: downloaded,
+	}
+	status.Rate = &heritrix.RateReport{
+		CurrentDocsPerSecond: float64(busy),
+		CurrentKiBPerSec:     float64(busy),
+	}
+	status.Load = &heritrix.LoadReport{BusyThreads: busy, TotalThreads: 2}
+	status.Elapsed = &heritrix.ElapsedReport{ElapsedMilliseconds: 1000 * job.progress}
+	status.Threads = &heritrix.ThreadReport{ToeCount: 2}
+	// Every tenth URI fails and every twentieth is out of scope
+	status.Frontier = &heritrix.FrontierReport{
+		TotalQueues:         1,
+		LastReachedState:    job.state(),
+		FailedFetchCount:    downloaded / 10,
+		DisregardedURICount: downloaded / 20,
+	}
+}
+
+func (job *Job) description() string {
+	switch {
+	case job.launched:
+		return "Active: " + job.state()
+	case job.built:
+		return "Ready"
+	default:
+		return "Unbuilt"
+	}
+}
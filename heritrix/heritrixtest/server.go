@@ -0,0 +1,405 @@
+// Package heritrixtest provides fake Heritrix engine for tests.
+//
+// The server speaks the same REST API as Heritrix, including digest
+// authentication, so heritrix.Client and everything built on top of it
+// can be tested without running crawler. Jobs move through scripted
+// controller states, failures and latency can be injected.
+package heritrixtest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"silence/heritrix"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/icholy/digest"
+)
+
+const Realm = "Authentication Required"
+
+// States the job goes through after launch when no script is given.
+var DefaultScript = []string{
+	heritrix.StatePreparing,
+	heritrix.StatePaused,
+	heritrix.StateRunning,
+	heritrix.StateFinished,
+}
+
+// Request received by the server, used for assertions in tests.
+type Request struct {
+	Method string
+	Path   string
+	Action string
+}
+
+// Fake Heritrix engine.
+type Server struct {
+	*httptest.Server
+
+	Username string
+	Password string
+	// When set, jobs live in directories under JobsDir and launch writes
+	// logs there, the same way Heritrix does. Must be set before AddJob.
+	JobsDir string
+
+	mu       sync.Mutex
+	nonces   map[string]bool
+	jobs     map[string]*Job
+	failures []*failure
+	latency  time.Duration
+	requests []Request
+
+	exitRequested bool
+}
+
+type failure struct {
+	method string
+	path   string
+	status int
+	skip   int
+	times  int
+}
+
+// Starts new server, it must be closed by Close.
+func NewServer(username, password string) *Server {
+	server := &Server{
+		Username: username,
+		Password: password,
+		nonces:   make(map[string]bool),
+		jobs:     make(map[string]*Job),
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(server.serveHTTP))
+	return server
+}
+
+// Adds job to the engine. Each status request after launch moves the job
+// to the next state of the script, the job stays in PAUSED until unpaused
+// and in the last state forever. Empty script means DefaultScript.
+func (server *Server) AddJob(name string, script ...string) *Job {
+	if len(script) == 0 {
+		script = DefaultScript
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	job := &Job{Name: name, original: script, script: script}
+	if server.JobsDir != "" {
+		job.dir = filepath.Join(server.JobsDir, name)
+	}
+	server.jobs[name] = job
+	return job
+}
+
+// Makes next times requests matching method and path fail with status.
+// Empty method matches any method.
+func (server *Server) Fail(method, path string, status int, times int) {
+	server.FailAfter(method, path, status, 0, times)
+}
+
+// Like Fail, but the first skip matching requests succeed.
+func (server *Server) FailAfter(method, path string, status int, skip int, times int) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.failures = append(server.failures, &failure{method, path, status, skip, times})
+}
+
+// Makes teardown of the job have no effect, as when Heritrix hangs
+// while closing the crawl.
+func (server *Server) SetStuckTeardown(name string, stuck bool) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.jobs[name].stuckTeardown = stuck
+}
+
+// Delays every response by d.
+func (server *Server) SetLatency(d time.Duration) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.latency = d
+}
+
+// Returns all authenticated requests received so far.
+func (server *Server) Requests() []Request {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return append([]Request(nil), server.requests...)
+}
+
+// Returns actions posted to the job in order.
+func (server *Server) Actions(name string) []string {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	var actions []string
+	for _, request := range server.requests {
+		if request.Path == heritrix.JobPath(name) && request.Action != "" {
+			actions = append(actions, request.Action)
+		}
+	}
+	return actions
+}
+
+// Returns crawler-beans the job had in its directory at each build, nil
+// for build without them. Empty when the job has no directory.
+func (server *Server) BuiltConfigs(name string) [][]byte {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	job, ok := server.jobs[name]
+	if !ok {
+		return nil
+	}
+	return append([][]byte(nil), job.builtConfigs...)
+}
+
+// Reports whether the engine was asked to exit the java process.
+func (server *Server) ExitRequested() bool {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return server.exitRequested
+}
+
+// Returns job added by AddJob.
+func (server *Server) Job(name string) *Job {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return server.jobs[name]
+}
+
+func (server *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	server.mu.Lock()
+	latency := server.latency
+	server.mu.Unlock()
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if !server.authenticate(w, r) {
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.requests = append(server.requests, Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Action: r.PostForm.Get(heritrix.ActionKey),
+	})
+
+	if status := server.failure(r); status != 0 {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	switch {
+	case r.URL.Path == heritrix.EnginePath:
+		server.serveEngine(w, r)
+	case strings.HasPrefix(r.URL.Path, heritrix.JobPath("")):
+		server.serveJob(w, r, strings.TrimPrefix(r.URL.Path, heritrix.JobPath("")))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (server *Server) failure(r *http.Request) int {
+	for i, failure := range server.failures {
+		if failure.method != "" && failure.method != r.Method {
+			continue
+		}
+		if failure.path != r.URL.Path {
+			continue
+		}
+		if failure.skip > 0 {
+			failure.skip--
+			return 0
+		}
+		failure.times--
+		if failure.times <= 0 {
+			server.failures = append(server.failures[:i], server.failures[i+1:]...)
+		}
+		return failure.status
+	}
+	return 0
+}
+
+func (server *Server) serveEngine(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		// rescan has no visible effect on fake engine
+		switch r.PostForm.Get(heritrix.ActionKey) {
+		case "exit java process":
+			server.exitRequested = true
+		case "create":
+			err := server.createJob(r.PostForm.Get("createpath"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		case "add":
+			path := r.PostForm.Get("addpath")
+			name := filepath.Base(path)
+			if _, exists := server.jobs[name]; exists {
+				http.Error(w, "job "+name+" already exists", http.StatusInternalServerError)
+				return
+			}
+			server.jobs[name] = &Job{Name: name, original: DefaultScript, script: DefaultScript, dir: path}
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	engine := heritrix.Engine{
+		HeritrixVersion: "3.4.0-heritrixtest",
+		Actions:         []string{"rescan", "add", "create"},
+	}
+	for _, job := range server.jobs {
+		engine.Jobs = append(engine.Jobs, heritrix.EngineJob{
+			ShortName:   job.Name,
+			URL:         server.URL + heritrix.JobPath(job.Name),
+			LaunchCount: job.launchCount,
+		})
+	}
+	writeXML(w, engine)
+}
+
+// Creates job with default script, its directory is created when the
+// server has JobsDir. Must be called while holding the lock.
+func (server *Server) createJob(name string) error {
+	if name == "" || strings.ContainsAny(name, "/\\") {
+		return fmt.Errorf("invalid job name %q", name)
+	}
+	if _, exists := server.jobs[name]; exists {
+		return fmt.Errorf("job %s already exists", name)
+	}
+
+	job := &Job{Name: name, original: DefaultScript, script: DefaultScript}
+	if server.JobsDir != "" {
+		job.dir = filepath.Join(server.JobsDir, name)
+		err := os.MkdirAll(job.dir, 0755)
+		if err != nil {
+			return err
+		}
+	}
+	server.jobs[name] = job
+	return nil
+}
+
+func (server *Server) serveJob(w http.ResponseWriter, r *http.Request, name string) {
+	job, ok := server.jobs[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		status := job.status()
+		job.advance()
+		writeXML(w, status)
+	case http.MethodPost:
+		if copyTo := r.PostForm.Get("copyTo"); copyTo != "" {
+			server.jobs[copyTo] = &Job{Name: copyTo, original: job.original, script: job.original}
+		} else {
+			err := job.do(r.PostForm.Get(heritrix.ActionKey))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		writeXML(w, job.status())
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Checks digest credentials, on failure sends new challenge.
+func (server *Server) authenticate(w http.ResponseWriter, r *http.Request) bool {
+	header := r.Header.Get("Authorization")
+	if digest.IsDigest(header) {
+		credentials, err := digest.ParseCredentials(header)
+		if err == nil && server.verify(r, credentials) {
+			return true
+		}
+	}
+
+	nonce := randomHex()
+	server.mu.Lock()
+	server.nonces[nonce] = true
+	server.mu.Unlock()
+
+	challenge := &digest.Challenge{
+		Realm:     Realm,
+		Nonce:     nonce,
+		Algorithm: "MD5",
+		QOP:       []string{"auth"},
+	}
+	w.Header().Set("WWW-Authenticate", challenge.String())
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	return false
+}
+
+func (server *Server) verify(r *http.Request, credentials *digest.Credentials) bool {
+	server.mu.Lock()
+	known := server.nonces[credentials.Nonce]
+	server.mu.Unlock()
+	if !known || credentials.Username != server.Username {
+		return false
+	}
+
+	challenge := &digest.Challenge{
+		Realm:     Realm,
+		Nonce:     credentials.Nonce,
+		Algorithm: "MD5",
+		QOP:       []string{"auth"},
+	}
+	expected, err := digest.Digest(challenge, digest.Options{
+		Method:   r.Method,
+		URI:      credentials.URI,
+		Count:    credentials.Nc,
+		Cnonce:   credentials.Cnonce,
+		Username: server.Username,
+		Password: server.Password,
+	})
+	if err != nil {
+		return false
+	}
+	return expected.Response == credentials.Response
+}
+
+func writeXML(w http.ResponseWriter, v any) {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", heritrix.AcceptXML)
+	w.Write([]byte(xml.Header))
+	w.Write(data)
+}
+
+func randomHex() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
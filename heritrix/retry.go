@@ -0,0 +1,118 @@
+package heritrix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"syscall"
+	"time"
+)
+
+// Decides which failed requests are sent again and how long to wait
+// before the next attempt. Zero value sends every request only once.
+type RetryPolicy struct {
+	// Number of attempts including the first one
+	Attempts int
+	// Delay before the second attempt, each next delay is multiplied
+	// by Multiplier up to MaxDelay
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Multiplier   float64
+	// Delay is randomly changed by up to this fraction, e.g. 0.2 is ±20 %
+	Jitter float64
+	// Status codes worth trying again, e.g. 503 while Heritrix is busy
+	StatusCodes []int
+	// Retry when Heritrix cannot be reached or connection breaks. Actions
+	// are not idempotent, so they are only sent again when the connection
+	// was refused and the request never reached Heritrix.
+	NetworkErrors bool
+
+	// Called before waiting for the next attempt, may be nil
+	OnRetry func(method, path string, attempt int, err error, delay time.Duration)
+}
+
+// Error returned when all attempts failed, it wraps the last error.
+type RetryError struct {
+	Attempts int
+	Err      error
+}
+
+func (err *RetryError) Error() string {
+	return fmt.Sprintf("failed after %d attempts: %s", err.Attempts, err.Err)
+}
+
+func (err *RetryError) Unwrap() error {
+	return err.Err
+}
+
+func (policy *RetryPolicy) retriable(method string, err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return slices.Contains(policy.StatusCodes, statusErr.StatusCode)
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	var urlErr *url.Error
+	if errors.As(err, &netErr) || errors.As(err, &urlErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		if method != http.MethodGet && !errors.Is(err, syscall.ECONNREFUSED) {
+			// Heritrix may have done the action and only the response was lost
+			return false
+		}
+		return policy.NetworkErrors
+	}
+	return false
+}
+
+// Returns delay before attempt, the first retry is attempt 2.
+func (policy *RetryPolicy) delay(attempt int) time.Duration {
+	multiplier := max(policy.Multiplier, 1)
+	delay := float64(policy.InitialDelay) * math.Pow(multiplier, float64(attempt-2))
+	if policy.MaxDelay > 0 {
+		delay = min(delay, float64(policy.MaxDelay))
+	}
+	if policy.Jitter > 0 {
+		delay += delay * policy.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(max(delay, 0))
+}
+
+// Runs fn until it succeeds, fails with error that is not retriable or
+// attempts run out.
+func (policy *RetryPolicy) do(ctx context.Context, method, path string, fn func() error) error {
+	attempts := max(policy.Attempts, 1)
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || !policy.retriable(method, err) || ctx.Err() != nil {
+			return err
+		}
+		if attempt == attempts {
+			break
+		}
+
+		delay := policy.delay(attempt + 1)
+		if policy.OnRetry != nil {
+			policy.OnRetry(method, path, attempt, err, delay)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+	if attempts == 1 {
+		return err
+	}
+	return &RetryError{Attempts: attempts, Err: err}
+}
@@ -0,0 +1,151 @@
+package heritrix
+
+import (
+	"encoding/xml"
+	"slices"
+)
+
+// Controller states reported in crawlControllerState.
+const (
+	StateNascent   = "NASCENT"
+	StatePreparing = "PREPARING"
+	StatePaused    = "PAUSED"
+	StatePausing   = "PAUSING"
+	StateRunning   = "RUNNING"
+	StateStopping  = "STOPPING"
+	StateFinished  = "FINISHED"
+	StateEmpty     = "EMPTY"
+)
+
+// Response of the /engine resource.
+type Engine struct {
+	XMLName         xml.Name    `xml:"engine"`
+	HeritrixVersion string      `xml:"heritrixVersion"`
+	Heap            HeapReport  `xml:"heapReport"`
+	JobsDir         string      `xml:"jobsDir"`
+	Actions         []string    `xml:"availableActions>value"`
+	Jobs            []EngineJob `xml:"jobs>value"`
+}
+
+type HeapReport struct {
+	UsedBytes  int64 `xml:"usedBytes"`
+	TotalBytes int64 `xml:"totalBytes"`
+	MaxBytes   int64 `xml:"maxBytes"`
+}
+
+// Short description of a job in the engine listing.
+type EngineJob struct {
+	ShortName         string `xml:"shortName"`
+	URL               string `xml:"url"`
+	IsProfile         bool   `xml:"isProfile"`
+	LaunchCount       int    `xml:"launchCount"`
+	LastLaunch        string `xml:"lastLaunch"`
+	StatusDescription string `xml:"statusDescription"`
+	PrimaryConfig     string `xml:"primaryConfig"`
+}
+
+// Returns job from the listing or nil if engine does not know it.
+func (engine *Engine) FindJob(name string) *EngineJob {
+	for i := range engine.Jobs {
+		if engine.Jobs[i].ShortName == name {
+			return &engine.Jobs[i]
+		}
+	}
+	return nil
+}
+
+// Response of the /engine/job/<name> resource.
+type Job struct {
+	XMLName           xml.Name `xml:"job"`
+	ShortName         string   `xml:"shortName"`
+	ControllerState   string   `xml:"crawlControllerState"`
+	ExitStatus        string   `xml:"crawlExitStatus"`
+	StatusDescription string   `xml:"statusDescription"`
+	Actions           []string `xml:"availableActions>value"`
+	LaunchCount       int      `xml:"launchCount"`
+	LastLaunch        string   `xml:"lastLaunch"`
+	IsProfile         bool     `xml:"isProfile"`
+	PrimaryConfig     string   `xml:"primaryConfig"`
+	URL               string   `xml:"url"`
+	IsRunning         bool     `xml:"isRunning"`
+	IsLaunchable      bool     `xml:"isLaunchable"`
+	AlertCount        int      `xml:"alertCount"`
+	AlertLogFilePath  string   `xml:"alertLogFilePath"`
+	CrawlLogFilePath  string   `xml:"crawlLogFilePath"`
+
+	// Reports are present only while the job is launched
+	URITotals  *URITotalsReport  `xml:"uriTotalsReport"`
+	SizeTotals *SizeTotalsReport `xml:"sizeTotalsReport"`
+	Rate       *RateReport       `xml:"rateReport"`
+	Load       *LoadReport       `xml:"loadReport"`
+	Elapsed    *ElapsedReport    `xml:"elapsedReport"`
+	Threads    *ThreadReport     `xml:"threadReport"`
+	Frontier   *FrontierReport   `xml:"frontierReport"`
+}
+
+type URITotalsReport struct {
+	DownloadedURICount int64 `xml:"downloadedUriCount"`
+	QueuedURICount     int64 `xml:"queuedUriCount"`
+	TotalURICount      int64 `xml:"totalUriCount"`
+	FutureURICount     int64 `xml:"futureUriCount"`
+}
+
+// Sizes of downloaded content in bytes, counts are numbers of URIs.
+type SizeTotalsReport struct {
+	DupByHash             int64 `xml:"dupByHash"`
+	DupByHashCount        int64 `xml:"dupByHashCount"`
+	Novel                 int64 `xml:"novel"`
+	NovelCount            int64 `xml:"novelCount"`
+	NotModified           int64 `xml:"notModified"`
+	NotModifiedCount      int64 `xml:"notModifiedCount"`
+	Total                 int64 `xml:"total"`
+	TotalCount            int64 `xml:"totalCount"`
+	WarcNovelContentBytes int64 `xml:"warcNovelContentBytes"`
+	WarcNovelURLs         int64 `xml:"warcNovelUrls"`
+}
+
+type RateReport struct {
+	CurrentDocsPerSecond float64 `xml:"currentDocsPerSecond"`
+	AverageDocsPerSecond float64 `xml:"averageDocsPerSecond"`
+	CurrentKiBPerSec     float64 `xml:"currentKiBPerSec"`
+	AverageKiBPerSec     float64 `xml:"averageKiBPerSec"`
+}
+
+type LoadReport struct {
+	BusyThreads       int     `xml:"busyThreads"`
+	TotalThreads      int     `xml:"totalThreads"`
+	CongestionRatio   float64 `xml:"congestionRatio"`
+	AverageQueueDepth float64 `xml:"averageQueueDepth"`
+	DeepestQueueDepth int64   `xml:"deepestQueueDepth"`
+}
+
+type ElapsedReport struct {
+	ElapsedMilliseconds int64  `xml:"elapsedMilliseconds"`
+	ElapsedPretty       string `xml:"elapsedPretty"`
+}
+
+type ThreadReport struct {
+	ToeCount   int      `xml:"toeCount"`
+	Steps      []string `xml:"steps>value"`
+	Processors []string `xml:"processors>value"`
+}
+
+// Queues of the frontier and URIs that left it without being downloaded.
+type FrontierReport struct {
+	TotalQueues         int    `xml:"totalQueues"`
+	InProcessQueues     int    `xml:"inProcessQueues"`
+	ReadyQueues         int    `xml:"readyQueues"`
+	SnoozedQueues       int    `xml:"snoozedQueues"`
+	ActiveQueues        int    `xml:"activeQueues"`
+	InactiveQueues      int    `xml:"inactiveQueues"`
+	IneligibleQueues    int    `xml:"ineligibleQueues"`
+	RetiredQueues       int    `xml:"retiredQueues"`
+	ExhaustedQueues     int    `xml:"exhaustedQueues"`
+	LastReachedState    string `xml:"lastReachedState"`
+	FailedFetchCount    int64  `xml:"failedFetchCount"`
+	DisregardedURICount int64  `xml:"disregardedUriCount"`
+}
+
+func (job *Job) HasAction(action string) bool {
+	return slices.Contains(job.Actions, action)
+}
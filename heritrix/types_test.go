@@ -0,0 +1,101 @@
+package heritrix_test
+
+import (
+	"encoding/xml"
+	"silence/heritrix"
+	"testing"
+)
+
+// Shortened response of running Heritrix 3 job.
+const runningJobXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<job>
+  <shortName>topics</shortName>
+  <crawlControllerState>RUNNING</crawlControllerState>
+  <statusDescription>Active: RUNNING</statusDescription>
+  <uriTotalsReport>
+    <downloadedUriCount>1520</downloadedUriCount>
+    <queuedUriCount>8043</queuedUriCount>
+    <totalUriCount>9563</totalUriCount>
+    <futureUriCount>12</futureUriCount>
+  </uriTotalsReport>
+  <sizeTotalsReport>
+    <dupByHash>0</dupByHash>
+    <dupByHashCount>0</dupByHashCount>
+    <novel>73412998</novel>
+    <novelCount>1520</novelCount>
+    <total>73412998</total>
+    <totalCount>1520</totalCount>
+  </sizeTotalsReport>
+  <rateReport>
+    <currentDocsPerSecond>12.5</currentDocsPerSecond>
+    <averageDocsPerSecond>10.1</averageDocsPerSecond>
+    <currentKiBPerSec>640</currentKiBPerSec>
+    <averageKiBPerSec>512</averageKiBPerSec>
+  </rateReport>
+  <loadReport>
+    <busyThreads>37</busyThreads>
+    <totalThreads>50</totalThreads>
+    <congestionRatio>1.25</congestionRatio>
+    <averageQueueDepth>3.5</averageQueueDepth>
+    <deepestQueueDepth>412</deepestQueueDepth>
+  </loadReport>
+  <elapsedReport>
+    <elapsedMilliseconds>150000</elapsedMilliseconds>
+    <elapsedPretty>2m30s</elapsedPretty>
+  </elapsedReport>
+  <threadReport>
+    <toeCount>50</toeCount>
+    <steps><value>37 ABOUT_TO_BEGIN_PROCESSOR</value><value>13 ABOUT_TO_GET_URI</value></steps>
+    <processors><value>20 fetchHttp</value></processors>
+  </threadReport>
+  <frontierReport>
+    <totalQueues>120</totalQueues>
+    <inProcessQueues>37</inProcessQueues>
+    <readyQueues>40</readyQueues>
+    <snoozedQueues>43</snoozedQueues>
+    <retiredQueues>2</retiredQueues>
+    <exhaustedQueues>15</exhaustedQueues>
+    <lastReachedState>RUNNING</lastReachedState>
+    <failedFetchCount>31</failedFetchCount>
+    <disregardedUriCount>204</disregardedUriCount>
+  </frontierReport>
+</job>`
+
+func TestJobReports(t *testing.T) {
+	job := new(heritrix.Job)
+	err := xml.Unmarshal([]byte(runningJobXML), job)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if job.URITotals == nil || job.URITotals.DownloadedURICount != 1520 || job.URITotals.QueuedURICount != 8043 {
+		t.Errorf("unexpected uri totals %+v", job.URITotals)
+	}
+	if job.SizeTotals == nil || job.SizeTotals.Total != 73412998 {
+		t.Errorf("unexpected size totals %+v", job.SizeTotals)
+	}
+	if job.Rate == nil || job.Rate.CurrentDocsPerSecond != 12.5 || job.Rate.CurrentKiBPerSec != 640 {
+		t.Errorf("unexpected rate %+v", job.Rate)
+	}
+	if job.Load == nil || job.Load.BusyThreads != 37 || job.Load.TotalThreads != 50 {
+		t.Errorf("unexpected load %+v", job.Load)
+	}
+	if job.Elapsed == nil || job.Elapsed.ElapsedMilliseconds != 150000 {
+		t.Errorf("unexpected elapsed %+v", job.Elapsed)
+	}
+	if job.Threads == nil || job.Threads.ToeCount != 50 || len(job.Threads.Steps) != 2 {
+		t.Errorf("unexpected threads %+v", job.Threads)
+	}
+	if job.Frontier == nil || job.Frontier.FailedFetchCount != 31 || job.Frontier.DisregardedURICount != 204 {
+		t.Errorf("unexpected frontier %+v", job.Frontier)
+	}
+
+	finished := new(heritrix.Job)
+	err = xml.Unmarshal([]byte(`<job><shortName>topics</shortName></job>`), finished)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if finished.URITotals != nil || finished.Rate != nil || finished.Frontier != nil {
+		t.Errorf("reports of job that is not launched should be nil: %+v", finished)
+	}
+}
@@ -15,11 +15,20 @@ type App struct {
 	cmd  *cobra.Command
 	args []string
 
-	WorkDirFlag *string
-	DebugFLag *bool
+	WorkDirFlag  *string
+	DebugFLag    *bool
+	LockFileFlag *string
+	ConfigFlag   *string
+	DryRunFlag   *bool
+	// Start crawl even over unfinished crawl in the Heritrix job
+	ForceFlag *bool
+	// Address of Prometheus metrics server, empty disables it
+	MetricsListenFlag *string
 
 	Log *slog.Logger
 	// WorkDir string
+
+	lock *Lock
 }
 
 func (app *App) initApp() error {
@@ -78,3 +87,72 @@ func (app *App) setWorkingDirectory(path string) error {
 
 	return nil
 }
+
+func (app *App) acquireLock() error {
+	path := DefaultLockPath()
+	if app.LockFileFlag != nil && *app.LockFileFlag != "" {
+		path = *app.LockFileFlag
+	}
+
+	lock, err := AcquireLock(path)
+	if err != nil {
+		var locked *LockedError
+		if errors.As(err, &locked) {
+			app.Log.Error(
+				"another instance is already running",
+				slog.String("lock", path),
+				slog.String(ErrorKey, err.Error()),
+			)
+		} else {
+			app.Log.Error(
+				"failed to acquire lock",
+				slog.String("lock", path),
+				slog.String(ErrorKey, err.Error()),
+			)
+		}
+		return err
+	}
+
+	if lock.Reclaimed != nil {
+		app.Log.Warn(
+			"reclaimed stale lock",
+			slog.String("lock", path),
+			slog.String("previous", lock.Reclaimed.String()),
+		)
+	}
+	app.Log.Debug("lock acquired", slog.String("lock", path))
+
+	app.lock = lock
+	return nil
+}
+
+func (app *App) releaseLock() {
+	if app.lock == nil {
+		return
+	}
+	err := app.lock.Release()
+	if err != nil {
+		app.Log.Error(
+			"failed to release lock",
+			slog.String("lock", app.lock.Path()),
+			slog.String(ErrorKey, err.Error()),
+		)
+		return
+	}
+	app.lock = nil
+}
+
+// Releases everything that would not survive os.Exit and exits.
+// Use this instead of os.Exit after the app was initialized.
+func (app *App) exit(status int) {
+	app.releaseLock()
+	os.Exit(status)
+}
+
+// Path to job config from flag, or the first default config that exists.
+func (app *App) jobConfigPath() (string, error) {
+	if app.ConfigFlag != nil && *app.ConfigFlag != "" {
+		return *app.ConfigFlag, nil
+	}
+	return findJobConfig()
+}
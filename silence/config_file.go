@@ -0,0 +1,127 @@
+package silence
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type ConfigFormat string
+
+const (
+	FormatJSON ConfigFormat = "json"
+	FormatYAML ConfigFormat = "yaml"
+)
+
+// Config files tried in working directory when no path is given, in order.
+var DefaultJobConfigPaths = []string{DefaultJobConfigPath, "job.yaml", "job.yml"}
+
+func ParseConfigFormat(s string) (ConfigFormat, error) {
+	switch strings.ToLower(s) {
+	case "json":
+		return FormatJSON, nil
+	case "yaml", "yml":
+		return FormatYAML, nil
+	}
+	return "", fmt.Errorf("unknown config format %s, use json or yaml", s)
+}
+
+// Returns format according to extension of path, for unknown extension
+// the content is sniffed. JSON document always starts with object.
+func detectConfigFormat(path string, data []byte) ConfigFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return FormatJSON
+	}
+	return FormatYAML
+}
+
+// Returns the first of DefaultJobConfigPaths that exists, or
+// DefaultJobConfigPath when none does.
+func findJobConfig() (string, error) {
+	for _, path := range DefaultJobConfigPaths {
+		_, err := os.Stat(path)
+		if err == nil {
+			return path, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+	return DefaultJobConfigPath, nil
+}
+
+// Decodes job config. YAML is converted to JSON first, so both formats
+// have exactly the same field semantics, including case insensitive keys.
+func unmarshalJob(path string, data []byte, job *Job) error {
+	if detectConfigFormat(path, data) == FormatJSON {
+		return json.Unmarshal(data, job)
+	}
+
+	var document any
+	err := yaml.Unmarshal(data, &document)
+	if err != nil {
+		return err
+	}
+	if document == nil {
+		return fmt.Errorf("%s is empty", path)
+	}
+
+	data, err = json.Marshal(document)
+	if err != nil {
+		return fmt.Errorf("%s cannot be represented as json: %w", path, err)
+	}
+	return json.Unmarshal(data, job)
+}
+
+// Encodes job config in given format.
+func MarshalJob(job *Job, format ConfigFormat) ([]byte, error) {
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if format == FormatJSON {
+		return data, nil
+	}
+
+	// JSON is valid YAML, decoding it into node keeps the order of fields
+	var node yaml.Node
+	err = yaml.Unmarshal(data, &node)
+	if err != nil {
+		return nil, err
+	}
+	clearStyle(&node)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	err = encoder.Encode(&node)
+	if err != nil {
+		return nil, err
+	}
+	err = encoder.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Switches flow style and quoting inherited from JSON to block style.
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
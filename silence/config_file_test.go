@@ -0,0 +1,76 @@
+package silence
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testJobJSON = `{
+  "Name": "topics",
+  "MaxLines": 100,
+  "MaxWaitSeconds": 3600,
+  "Config": {"Operator": "op", "ToeThreads": 50, "CrawlNameSuffix": "x"},
+  "Heritrix": {"Home": "/opt/heritrix", "Command": ["bin/heritrix", "-a", "a:b"]}
+}`
+
+const testJobYAML = `
+name: topics
+maxlines: 100
+MaxWaitSeconds: 3600
+Config:
+  operator: op
+  ToeThreads: 50
+  CrawlNameSuffix: x
+Heritrix:
+  Home: /opt/heritrix
+  Command: [bin/heritrix, -a, "a:b"]
+`
+
+func TestUnmarshalJobFormats(t *testing.T) {
+	fromJSON := DefaultJob("")
+	err := unmarshalJob("job.json", []byte(testJobJSON), fromJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fromYAML := DefaultJob("")
+	err = unmarshalJob("job.yaml", []byte(testJobYAML), fromYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sniffed := DefaultJob("")
+	err = unmarshalJob("job.conf", []byte(testJobYAML), sniffed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(fromJSON, fromYAML) || !reflect.DeepEqual(fromJSON, sniffed) {
+		t.Fatalf("json and yaml differ:\n%+v\n%+v", fromJSON, fromYAML)
+	}
+	if fromYAML.Config.ToeThreads != 50 || fromYAML.SeedsPath != "seeds.txt" {
+		t.Fatalf("unexpected values %+v", fromYAML)
+	}
+}
+
+func TestMarshalJobRoundTrip(t *testing.T) {
+	for _, format := range []ConfigFormat{FormatJSON, FormatYAML} {
+		job := DefaultJob("")
+		job.Name = "true"
+		job.Config.Operator = "123"
+
+		data, err := MarshalJob(job, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		loaded := DefaultJob("")
+		err = unmarshalJob("job."+string(format), data, loaded)
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", format, err, data)
+		}
+		if !reflect.DeepEqual(job, loaded) {
+			t.Fatalf("%s round trip changed job:\n%s", format, data)
+		}
+	}
+}
@@ -1,133 +1,227 @@
 package silence
 
 import (
-	"encoding/xml"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"io"
+	"io/fs"
 	"log/slog"
-	"net/http"
-	"net/url"
 	"os"
 	"path"
-	"strings"
-	"text/template"
+	"path/filepath"
+	"silence/heritrix"
 	"time"
 )
 
-const (
-	AcceptHeaderKey = "Accept"
-	AcceptXML       = "application/xml"
-)
-
-const ActionKey = "action"
-
 type Crawl struct {
 	ID        int
 	SeedsFile string
-	Job       *Job
-	endpoint  string
+	Timestamp string
+	Status    CrawlStatus
+	// Dedicated Heritrix job of the part, see PartJobsConfig
+	HeritrixJob string `json:",omitempty"`
+
+	Job *Job `json:"-"`
+
+	// Values known after crawler-beans were rendered
+	name           string
+	warcStorePaths []string
+	// Crawler-beans written into the Heritrix job
+	beansFile string
+	// Heritrix job the crawl runs in
+	slot *crawlerSlot
+	// Directory of the dedicated Heritrix job and whether Heritrix knows it
+	partJobDir    string
+	partJobExists bool
+	// Last status received from Heritrix
+	status *heritrix.Job
+	// Directory with Heritrix logs of the crawl
+	logsDir string
+
+	report CrawlReport
 }
 
 func NewCrawl(id int, timestamp string, directory string, job *Job) *Crawl {
 	seedsFile := fmt.Sprintf("seeds-%s-%03d.txt", timestamp, id)
 	seedsFile = path.Join(directory, seedsFile)
-	endpoint := "engine/job/" + job.Name
-	return &Crawl{id, seedsFile, job, endpoint}
+	crawl := &Crawl{
+		ID:        id,
+		SeedsFile: seedsFile,
+		Timestamp: timestamp,
+		Status:    CrawlQueued,
+	}
+	crawl.attach(job)
+	return crawl
+}
+
+// Binds crawl to the job, used also for crawls loaded from saved state.
+func (crawl *Crawl) attach(job *Job) {
+	crawl.Job = job
+}
+
+func (crawl *Crawl) client() *heritrix.Client {
+	return crawl.slot.endpoint.client
+}
+
+// Name of the Heritrix job the crawl runs in.
+func (crawl *Crawl) jobName() string {
+	if crawl.HeritrixJob != "" {
+		return crawl.HeritrixJob
+	}
+	return crawl.slot.jobName
 }
 
 func (crawl *Crawl) String() string {
-	return fmt.Sprintf("id:%d seeds:%s", crawl.ID, crawl.SeedsFile)
+	return fmt.Sprintf("id:%d seeds:%s status:%s", crawl.ID, crawl.SeedsFile, crawl.Status)
 }
 
-func (crawl *Crawl) Run(app *App) error {
+// Runs the crawl. When ctx is canceled, the Heritrix job is terminated
+// and torn down, harvest is cleaned but seeds are kept, so the crawl can
+// be run again, and error wrapping ctx.Err() is returned.
+func (crawl *Crawl) Run(ctx context.Context, app *App) error {
 	app.Log.Debug(
 		fmt.Sprintf("crawl %d is running", crawl.ID),
 	)
 
-	err := crawl.pingHeritrix(app)
+	started := time.Now()
+	crawl.report = CrawlReport{Started: &started}
+	defer func() {
+		finished := time.Now()
+		crawl.report.Finished = &finished
+	}()
+
+	seedCount, err := countLines(crawl.SeedsFile)
 	if err != nil {
+		app.Log.Error(
+			fmt.Sprintf("failed to count seeds in %s", crawl.SeedsFile),
+			slog.String(ErrorKey, err.Error()),
+		)
 		return err
 	}
+	crawl.report.SeedCount = seedCount
 
-	err = crawl.createCrawlBeans()
+	err = crawl.pingHeritrix(ctx, app)
+	if err != nil {
+		return err
+	}
+
+	if crawl.HeritrixJob != "" {
+		err = crawl.preparePartJob(ctx, app)
+		if err != nil {
+			app.Log.Error(
+				"failed to prepare heritrix job of the part",
+				slog.String(ErrorKey, err.Error()),
+			)
+			return err
+		}
+	}
+
+	beansPath, err := crawl.beansPath(ctx)
 	if err != nil {
 		app.Log.Error(
-			fmt.Sprintf("failed to create %s", CrawlerBeansName),
+			"failed to find where to write crawler-beans",
 			slog.String(ErrorKey, err.Error()),
 		)
 		return err
 	}
 
-	err = crawl.build()
+	// Nothing is written into the job before it is known to be unused
+	err = crawl.preflight(ctx, app, beansPath)
 	if err != nil {
 		app.Log.Error(
-			"build failed",
+			"refusing to start crawl",
 			slog.String(ErrorKey, err.Error()),
 		)
 		return err
 	}
 
-	err = crawl.launch()
+	err = crawl.createCrawlBeans(beansPath)
 	if err != nil {
 		app.Log.Error(
-			"launch failed",
+			fmt.Sprintf("failed to create %s", beansPath),
 			slog.String(ErrorKey, err.Error()),
 		)
 		return err
 	}
+	crawl.copyCrawlBeans(app)
 
-	err = crawl.unpause()
+	err = crawl.addPartJob(ctx, app)
 	if err != nil {
 		app.Log.Error(
-			"unpause failed",
+			"failed to add heritrix job of the part",
 			slog.String(ErrorKey, err.Error()),
 		)
 		return err
 	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
 
-	// TODO: Add monitoring
-	err = crawl.await(app)
+	err = crawl.build(ctx, app)
 	if err != nil {
+		if ctx.Err() != nil {
+			return crawl.interrupt(ctx, app)
+		}
 		app.Log.Error(
-			"await failed",
+			"build failed",
 			slog.String(ErrorKey, err.Error()),
 		)
 		return err
 	}
 
-	err = crawl.terminate()
+	err = crawl.launch(ctx, app)
 	if err != nil {
+		if ctx.Err() != nil {
+			return crawl.interrupt(ctx, app)
+		}
 		app.Log.Error(
-			"terminate failed",
+			"launch failed",
 			slog.String(ErrorKey, err.Error()),
 		)
 		return err
 	}
 
-	err = crawl.teardown()
+	err = crawl.unpause(ctx, app)
 	if err != nil {
+		if ctx.Err() != nil {
+			return crawl.interrupt(ctx, app)
+		}
 		app.Log.Error(
-			"teardown failed",
+			"unpause failed",
 			slog.String(ErrorKey, err.Error()),
 		)
 		return err
 	}
 
-	// ---
-	// TODO: Wait for crawl to teardown
-	err = crawl.awaitTeardown()
+	err = crawl.await(ctx, app)
 	if err != nil {
+		if ctx.Err() != nil {
+			return crawl.interrupt(ctx, app)
+		}
 		app.Log.Error(
-			"error when waiting for teardown to finish",
+			"await failed",
 			slog.String(ErrorKey, err.Error()),
 		)
+		if heritrixUnreachable(err) {
+			crawl.abandon(context.WithoutCancel(ctx), app)
+			return err
+		}
+		// Heritrix answers, only the crawl is stopped
+		stopErr := crawl.stop(context.WithoutCancel(ctx), app)
+		return errors.Join(err, stopErr)
+	}
+
+	// Crawl must be stopped even if interrupted in the meantime
+	err = crawl.stop(context.WithoutCancel(ctx), app)
+	if err != nil {
 		return err
 	}
-	
+
 	app.Log.Debug(
 		fmt.Sprintf("cleaning crawl %d", crawl.ID),
 	)
-	err = crawl.clean()
+	err = crawl.clean(app)
 	if err != nil {
 		app.Log.Error(
 			"clean failed",
@@ -139,166 +233,507 @@ func (crawl *Crawl) Run(app *App) error {
 	return nil
 }
 
-func (crawl *Crawl) pingHeritrix(app *App) error {
-	const endpoint = "/engine"
-	response, err := crawl.request(http.MethodGet, endpoint, nil)
+// Terminates and tears down the Heritrix job and waits until it is torn down.
+func (crawl *Crawl) stop(ctx context.Context, app *App) error {
+	err := crawl.terminate(ctx, app)
 	if err != nil {
 		app.Log.Error(
-			"error when pinging heritrix",
+			"terminate failed",
 			slog.String(ErrorKey, err.Error()),
 		)
 		return err
 	}
-	defer response.Body.Close()
 
-	if response.StatusCode != 200 {
-		err = fmt.Errorf("error code recieved")
+	err = crawl.teardown(ctx, app)
+	if err != nil {
 		app.Log.Error(
-			"error code returned from heritrix",
-			slog.Int(ReturnStatusKey, response.StatusCode),
+			"teardown failed",
 			slog.String(ErrorKey, err.Error()),
 		)
 		return err
 	}
 
-	app.Log.Debug(
-		"ping from heritrix",
-		slog.Int(ReturnStatusKey, response.StatusCode),
-	)
+	err = crawl.awaitTeardown(ctx, app)
+	if err != nil {
+		app.Log.Error(
+			"error when waiting for teardown to finish",
+			slog.String(ErrorKey, err.Error()),
+		)
+		return err
+	}
 
-	// Possible addition of checking status of crawls
 	return nil
 }
 
-func (crawl *Crawl) request(method string, path string, values url.Values) (*http.Response, error) {
-	// net/url is really bad, I should just use string manipulation instead
-	addr := crawl.Job.CrawlerAddress
-	if !strings.HasPrefix(addr, "http") {
-		addr = "https://" + addr
+// Reports whether Heritrix did not answer even after retries, other
+// errors such as client errors or unparsable responses come from Heritrix
+// that still runs.
+func heritrixUnreachable(err error) bool {
+	var retryErr *heritrix.RetryError
+	return errors.As(err, &retryErr) || instanceDown(err)
+}
+
+// Called when Heritrix stopped answering during the crawl and all retries
+// failed. Sends terminate and tries to shut Heritrix down, both may fail
+// as well. Heritrix started by silence is stopped by the supervisor when
+// the job ends.
+func (crawl *Crawl) abandon(ctx context.Context, app *App) {
+	app.Log.Warn(
+		fmt.Sprintf("heritrix does not answer, abandoning crawl %d", crawl.ID),
+	)
+
+	err := crawl.client().Terminate(ctx, crawl.jobName())
+	if err != nil {
+		app.Log.Error(
+			"terminate of abandoned crawl failed",
+			slog.String(ErrorKey, err.Error()),
+		)
 	}
 
-	// slog.Info(addr)
-	address, err := url.Parse(addr)
+	if crawl.Job.Heritrix.managed() {
+		return
+	}
+	err = crawl.client().Exit(ctx)
 	if err != nil {
-		return nil, err
+		app.Log.Error(
+			"failed to shut down heritrix, it must be checked by operator",
+			slog.String(ErrorKey, err.Error()),
+		)
+		return
 	}
-	address.Path = path
-	// slog.Info(address.String(), "path", path)
+	app.Log.Info("heritrix was asked to exit")
+}
 
-	var body io.Reader
-	if method == http.MethodGet || values == nil {
-		body = http.NoBody
-	} else {
-		body = strings.NewReader(values.Encode())
+// Reports whether the job was launched. Launch may have been sent just
+// before interrupt, so the status is checked again, the last known status
+// is used when Heritrix does not answer. Built job is NASCENT until launch.
+func (crawl *Crawl) launched(ctx context.Context) bool {
+	status, err := crawl.client().Job(ctx, crawl.jobName())
+	if err == nil {
+		crawl.observe(status)
+	}
+	if crawl.status == nil {
+		return false
 	}
+	state := crawl.status.ControllerState
+	return state != "" && state != heritrix.StateNascent
+}
+
+// Safely stops interrupted crawl. Seeds are kept for the next run.
+func (crawl *Crawl) interrupt(ctx context.Context, app *App) error {
+	app.Log.Warn(
+		fmt.Sprintf("crawl %d was interrupted, stopping heritrix job", crawl.ID),
+	)
 
-	request, err := http.NewRequest(method, address.String(), body)
+	stopCtx := context.WithoutCancel(ctx)
+	var err error
+	if crawl.launched(stopCtx) {
+		err = crawl.stop(stopCtx, app)
+	} else {
+		// Job that was not launched never reaches FINISHED, it is only torn down
+		err = crawl.teardown(stopCtx, app)
+		if err == nil {
+			err = crawl.awaitTeardown(stopCtx, app)
+		}
+	}
 	if err != nil {
-		return nil, err
+		return errors.Join(ctx.Err(), err)
 	}
 
-	request.Header.Set(AcceptHeaderKey, AcceptXML)
-	response, err := crawl.Job.client.Do(request)
+	err = crawl.cleanHarvest(app)
 	if err != nil {
-		return nil, err
+		app.Log.Error(
+			"clean failed",
+			slog.String(ErrorKey, err.Error()),
+		)
+		return errors.Join(ctx.Err(), err)
 	}
 
-	return response, nil
+	return ctx.Err()
 }
 
-func (crawl *Crawl) createCrawlBeans() error {
-	beansTemplate, err := template.ParseFiles(crawl.Job.TemplatePath)
+func (crawl *Crawl) pingHeritrix(ctx context.Context, app *App) error {
+	engine, err := crawl.client().Engine(ctx)
 	if err != nil {
+		app.Log.Error(
+			"error when pinging heritrix",
+			slog.String(ErrorKey, err.Error()),
+		)
 		return err
 	}
 
-	// Add crawl specific values to config
-	// It is importatnt to create new copy for every iteration
+	app.Log.Debug(
+		"ping from heritrix",
+		slog.String("version", engine.HeritrixVersion),
+	)
+
+	// Possible addition of checking status of crawls
+	return nil
+}
+
+// Returns path of crawler-beans of the Heritrix job. The job lives in
+// JobsDir of the endpoint when it is set, otherwise Heritrix is asked for
+// primary config of the job, which must be reachable from here.
+// Dedicated job of the part has its directory known from preparePartJob.
+func (crawl *Crawl) beansPath(ctx context.Context) (string, error) {
+	if crawl.partJobDir != "" {
+		return filepath.Join(crawl.partJobDir, CrawlerBeansName), nil
+	}
+	if jobsDir := crawl.slot.endpoint.JobsDir; jobsDir != "" {
+		jobDir := filepath.Join(jobsDir, crawl.jobName())
+		info, err := os.Stat(jobDir)
+		if err != nil {
+			return "", fmt.Errorf("heritrix job directory: %w", err)
+		}
+		if !info.IsDir() {
+			return "", fmt.Errorf("heritrix job directory %s is not a directory", jobDir)
+		}
+		return filepath.Join(jobDir, CrawlerBeansName), nil
+	}
+	status, err := crawl.client().Job(ctx, crawl.jobName())
+	if err != nil {
+		return "", err
+	}
+	if status.PrimaryConfig == "" {
+		return "", fmt.Errorf("heritrix job %s has no primary config", crawl.jobName())
+	}
+	return status.PrimaryConfig, nil
+}
+
+// Returns copy of job config with crawl specific values.
+func (crawl *Crawl) config() *JobConfig {
+	// It is importatnt to create new copy for every crawl
 	config := crawl.Job.Config.Copy()
 	config.seedsFile = crawl.SeedsFile
 	config.id = crawl.ID
 	config.crawlType = crawl.Job.Name
+	return config
+}
 
-	crawlerBeansFile, err := os.Create(CrawlerBeansName)
+// Renders crawler-beans of the crawl from template into file at path.
+// The file is replaced atomically, Heritrix never reads half written config.
+func (crawl *Crawl) createCrawlBeans(path string) error {
+	rendered, err := crawl.renderCrawlBeans()
 	if err != nil {
 		return err
 	}
-	defer crawlerBeansFile.Close()
 
-	err = beansTemplate.Execute(crawlerBeansFile, config)
+	err = writeFileAtomic(path, rendered, 0644)
 	if err != nil {
 		return err
 	}
 
+	crawl.beansFile = path
+	crawl.name = crawl.config().CrawlName()
+	crawl.warcStorePaths = parseWarcStorePaths(rendered)
 	return nil
 }
 
-func (crawl *Crawl) clean() error {
-	err := os.Remove(crawl.SeedsFile)
+// Renders crawler-beans of the crawl into memory.
+func (crawl *Crawl) renderCrawlBeans() ([]byte, error) {
+	beansTemplate, err := crawl.Job.template()
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	var rendered bytes.Buffer
+	err = beansTemplate.Execute(&rendered, crawl.config())
+	if err != nil {
+		return nil, err
 	}
+	return rendered.Bytes(), nil
+}
 
-	// err = os.Remove(CrawlerBeansName)
-	// if err != nil {
-	// 	return err
-	// }
+// Keeps copy of rendered crawler-beans next to the report of the run,
+// so it is known what the part was crawled with. Failure is not fatal.
+func (crawl *Crawl) copyCrawlBeans(app *App) {
+	data, err := os.ReadFile(crawl.beansFile)
+	if err == nil {
+		name := beansCopyName(crawl.Job.started, crawl.ID)
+		err = writeFileAtomic(name, data, 0644)
+		if err == nil {
+			crawl.report.BeansCopy = name
+			return
+		}
+	}
+	app.Log.Error(
+		"failed to keep copy of crawler-beans",
+		slog.String(ErrorKey, err.Error()),
+	)
+}
+
+// Cleans after the crawl. It tries every step even if some of them fail.
+func (crawl *Crawl) clean(app *App) error {
+	var errs []error
 
+	err := crawl.cleanHarvest(app)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	err = os.Remove(crawl.SeedsFile)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	// Copy next to the report is kept
+	if crawl.beansFile != "" {
+		err = os.Remove(crawl.beansFile)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Recovers WARCs and archives logs left by Heritrix.
+func (crawl *Crawl) cleanHarvest(app *App) (err error) {
+	defer func() {
+		crawl.report.Cleanup.Done = err == nil
+		if err != nil {
+			crawl.report.Cleanup.Error = err.Error()
+		}
+	}()
+
+	var errs []error
+
+	err = crawl.recoverWarcs(app)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to recover open warcs: %w", err))
+	}
+
+	err = crawl.archiveLogs(app)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to archive crawl logs: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (crawl *Crawl) recoverWarcs(app *App) error {
+	dirs := crawl.resolveWarcStorePaths(app)
+	if len(dirs) == 0 {
+		app.Log.Warn("warc store path is unknown, open warcs are not recovered")
+		return nil
+	}
+
+	var recovered, untouched int
+	for _, dir := range dirs {
+		recovery, err := recoverOpenWarcs(app, dir)
+		recovered += len(recovery.Recovered)
+		untouched += len(recovery.Untouched)
+		crawl.report.Cleanup.WarcsRecovered = recovered
+		crawl.report.Cleanup.WarcsUntouched = untouched
+		if err != nil {
+			return err
+		}
+	}
+
+	app.Log.Info(
+		"open warcs processed",
+		slog.Int("recovered", recovered),
+		slog.Int("untouched", untouched),
+	)
 	return nil
 }
 
-func (crawl *Crawl) doAction(action string) error {
-	data := url.Values{}
-	data.Add(ActionKey, action)
-	response, err := crawl.request(http.MethodPost, crawl.endpoint, data)
+// Returns absolute WARC store paths from rendered crawler-beans.
+func (crawl *Crawl) resolveWarcStorePaths(app *App) []string {
+	if len(crawl.warcStorePaths) == 0 {
+		app.Log.Warn(
+			fmt.Sprintf("no %s found in %s", warcStorePathsKey, CrawlerBeansName),
+		)
+		return nil
+	}
+
+	return resolveStorePaths(app, crawl.warcStorePaths, crawl.jobDir())
+}
+
+// Makes WARC store paths absolute, Heritrix resolves relative paths
+// against the job directory. Relative paths are skipped when jobDir is empty.
+func resolveStorePaths(app *App, storePaths []string, jobDir string) []string {
+	var dirs []string
+	for _, dir := range storePaths {
+		if !filepath.IsAbs(dir) {
+			if jobDir == "" {
+				app.Log.Warn(
+					"job directory is unknown, relative warc store path is skipped",
+					slog.String("path", dir),
+				)
+				continue
+			}
+			dir = filepath.Join(jobDir, dir)
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// Directory of the Heritrix job or empty string if Heritrix did not report it.
+func (crawl *Crawl) jobDir() string {
+	if crawl.partJobDir != "" {
+		return crawl.partJobDir
+	}
+	if crawl.status == nil || crawl.status.PrimaryConfig == "" {
+		if crawl.beansFile != "" {
+			return filepath.Dir(crawl.beansFile)
+		}
+		return ""
+	}
+	return filepath.Dir(crawl.status.PrimaryConfig)
+}
+
+// Remembers status received from Heritrix. Path to logs and controller
+// state are reported only while the crawl is running, so they are kept separately.
+func (crawl *Crawl) observe(status *heritrix.Job) {
+	crawl.status = status
+	crawl.Job.metrics.observe(crawl.ID, status)
+	// Torn down job has no state, report keeps the last one
+	if status.ControllerState != "" {
+		crawl.report.ControllerState = status.ControllerState
+		crawl.report.ExitStatus = status.ExitStatus
+		crawl.report.StatusDescription = status.StatusDescription
+	}
+	if status.CrawlLogFilePath != "" {
+		crawl.logsDir = filepath.Dir(status.CrawlLogFilePath)
+	}
+}
+
+// Returns progress of the crawl for logging, reports missing in the status
+// are left out.
+func progressAttrs(status *heritrix.Job) []any {
+	var attrs []any
+	if status.URITotals != nil {
+		attrs = append(attrs,
+			slog.Int64("downloaded", status.URITotals.DownloadedURICount),
+			slog.Int64("queued", status.URITotals.QueuedURICount),
+			slog.Int64("future", status.URITotals.FutureURICount),
+		)
+	}
+	if status.SizeTotals != nil {
+		attrs = append(attrs, slog.Int64("bytes", status.SizeTotals.Total))
+	}
+	if status.Rate != nil {
+		attrs = append(attrs,
+			slog.Float64("docs_per_s", status.Rate.CurrentDocsPerSecond),
+			slog.Float64("kib_per_s", status.Rate.CurrentKiBPerSec),
+		)
+	}
+	if status.Frontier != nil {
+		attrs = append(attrs,
+			slog.Int64("failed", status.Frontier.FailedFetchCount),
+			slog.Int64("disregarded", status.Frontier.DisregardedURICount),
+		)
+	}
+	if status.Load != nil {
+		attrs = append(attrs,
+			slog.Int("busy_threads", status.Load.BusyThreads),
+			slog.Int("threads", status.Load.TotalThreads),
+		)
+	}
+	if status.Threads != nil {
+		attrs = append(attrs, slog.Int("toes", status.Threads.ToeCount))
+	}
+	if status.Elapsed != nil {
+		elapsed := time.Duration(status.Elapsed.ElapsedMilliseconds) * time.Millisecond
+		attrs = append(attrs, slog.Duration("elapsed", elapsed))
+	}
+	return attrs
+}
+
+// Archives Heritrix logs of the crawl into harvest directory.
+func (crawl *Crawl) archiveLogs(app *App) error {
+	logsDir := crawl.logsDir
+	if logsDir == "" {
+		jobDir := crawl.jobDir()
+		if jobDir == "" {
+			app.Log.Warn("job directory is unknown, crawl logs are not archived")
+			return nil
+		}
+		// Heritrix points latest to directory of the last launch
+		logsDir = filepath.Join(jobDir, "latest", "logs")
+		// Job that was never launched has no logs
+		_, err := os.Stat(logsDir)
+		if errors.Is(err, fs.ErrNotExist) {
+			app.Log.Warn("no crawl logs found", slog.String("dir", logsDir))
+			return nil
+		}
+	}
+
+	harvestDir := "."
+	if dirs := crawl.resolveWarcStorePaths(app); len(dirs) > 0 {
+		harvestDir = dirs[0]
+	}
+	name := crawl.name
+	if name == "" {
+		name = fmt.Sprintf("%s-Part%d", crawl.Job.Name, crawl.ID)
+	}
+	archivePath, err := unusedPath(filepath.Join(harvestDir, LogArchiveDirectory), name, ".tar.gz")
 	if err != nil {
 		return err
 	}
-	defer response.Body.Close()
 
-	if response.StatusCode != 200 {
-		err = fmt.Errorf("non ok status code recieved (%s)", response.Status)
+	archived, err := archiveLogs(logsDir, archivePath)
+	if err != nil {
 		return err
 	}
+	if len(archived) == 0 {
+		app.Log.Warn("no crawl logs found", slog.String("dir", logsDir))
+		return nil
+	}
+
+	crawl.report.Cleanup.LogArchive = archivePath
+	app.Log.Info(
+		"crawl logs archived",
+		slog.String("archive", archivePath),
+		slog.Int("files", len(archived)),
+	)
+	return nil
+}
 
-	// body, err := io.ReadAll(response.Body)
-	// if err != nil {
-	// 	return err
-	// }
-	// fmt.Println(string(body))
+// Sends action to Heritrix and waits until the job reaches state expected
+// after it, see actionStates.
+func (crawl *Crawl) doAction(ctx context.Context, app *App, verb string, action func(context.Context, string) error) error {
+	started := time.Now()
+	defer func() {
+		crawl.Job.metrics.observeAction(verb, time.Since(started))
+	}()
 
-	// --
+	err := action(ctx, crawl.jobName())
+	if err != nil {
+		return err
+	}
 
-	time.Sleep(5 * time.Second)
-	return nil
+	return crawl.waitFor(ctx, app, verb)
 }
 
-func (crawl *Crawl) build() error {
-	const action = "build"
-	return crawl.doAction(action)
+func (crawl *Crawl) build(ctx context.Context, app *App) error {
+	return crawl.doAction(ctx, app, "build", crawl.client().Build)
 }
 
-func (crawl *Crawl) launch() error {
-	const action = "launch"
-	return crawl.doAction(action)
+func (crawl *Crawl) launch(ctx context.Context, app *App) error {
+	return crawl.doAction(ctx, app, "launch", crawl.client().Launch)
 }
 
-func (crawl *Crawl) unpause() error {
-	const action = "unpause"
-	return crawl.doAction(action)
+func (crawl *Crawl) unpause(ctx context.Context, app *App) error {
+	return crawl.doAction(ctx, app, "unpause", crawl.client().Unpause)
 }
 
-func (crawl *Crawl) terminate() error {
-	const action = "terminate"
-	return crawl.doAction(action)
+func (crawl *Crawl) checkpoint(ctx context.Context, app *App) error {
+	return crawl.doAction(ctx, app, "checkpoint", crawl.client().Checkpoint)
 }
 
-func (crawl *Crawl) teardown() error {
-	const action = "teardown"
-	return crawl.doAction(action)
+func (crawl *Crawl) terminate(ctx context.Context, app *App) error {
+	return crawl.doAction(ctx, app, "terminate", crawl.client().Terminate)
 }
 
-func (crawl *Crawl) await(app *App) error {
+// Sends teardown, the teardown itself is awaited by awaitTeardown.
+func (crawl *Crawl) teardown(ctx context.Context, app *App) error {
+	return crawl.doAction(ctx, app, "teardown", crawl.client().Teardown)
+}
+
+func (crawl *Crawl) await(ctx context.Context, app *App) error {
 	app.Log.Info(
 		"waiting for crawl to finish",
 		slog.Int("max_wait_s", crawl.Job.MaxWaitSeconds),
@@ -306,28 +741,18 @@ func (crawl *Crawl) await(app *App) error {
 
 	maxDuration := time.Duration(crawl.Job.MaxWaitSeconds) * time.Second
 	done := time.After(maxDuration)
+	crawl.Job.metrics.setDeadline(crawl.ID, time.Now().Add(maxDuration))
+	defer crawl.Job.metrics.setDeadline(crawl.ID, time.Time{})
 
-	for {
-		response, err := crawl.request(http.MethodGet, crawl.endpoint, nil)
-		if err != nil {
-			app.Log.Error(
-				"error when checking crawl status",
-				slog.String(ErrorKey, err.Error()),
-			)
-			return err
-		}
-
-		if response.StatusCode != 200 {
-			err = fmt.Errorf("response returned code %s", response.Status)
-			app.Log.Error(
-				"error when checking crawl status",
-				slog.String(ErrorKey, err.Error()),
-				slog.Int(ReturnStatusKey, response.StatusCode),
-			)
-		}
+	var stall *stallDetector
+	if crawl.Job.Stall.enabled() {
+		stall = newStallDetector(crawl.Job.Stall)
+	}
 
-		body, err := io.ReadAll(response.Body)
+	for {
+		status, err := crawl.client().Job(ctx, crawl.jobName())
 		if err != nil {
+			crawl.Job.metrics.pollFailed()
 			app.Log.Error(
 				"error when checking crawl status",
 				slog.String(ErrorKey, err.Error()),
@@ -335,34 +760,35 @@ func (crawl *Crawl) await(app *App) error {
 			return err
 		}
 
-		crawlResponse := new(CrawlResponse)
+		crawl.observe(status)
 
-		err = xml.Unmarshal(body, crawlResponse)
-		if err != nil {
-			app.Log.Error(
-				"error when checking crawl status",
-				slog.String(ErrorKey, err.Error()),
-			)
-			return err
+		attrs := []any{
+			slog.String("state", status.ControllerState),
+			slog.String("exit_status", status.ExitStatus),
+			slog.String("exit_desc", status.StatusDescription),
 		}
+		app.Log.Info("crawl status", append(attrs, progressAttrs(status)...)...)
 
-		app.Log.Info(
-			"crawl status",
-			slog.String("state", crawlResponse.ControllerState),
-			slog.String("exit_status", crawlResponse.ExitStatus),
-			slog.String("exit_desc", crawlResponse.ExitDescription),
-		)
-
-		if crawlResponse.ControllerState == "FINISHED" {
+		if status.ControllerState == heritrix.StateFinished {
 			app.Log.Info("finished, terminating")
 			return nil
 		}
 
-		response.Body.Close()
+		if stall != nil {
+			if reason, stalled := stall.check(status, time.Now()); stalled {
+				crawl.report.Stalled = true
+				app.Log.Warn(
+					"crawl is stalled, terminating",
+					slog.String("reason", reason),
+				)
+				return crawl.checkpointStalled(ctx, app)
+			}
+		}
 
 		select {
 		case <-done:
 			{
+				crawl.report.TimedOut = true
 				app.Log.Warn("crawl did not finish before timeout, terminating")
 				return nil
 			}
@@ -370,59 +796,30 @@ func (crawl *Crawl) await(app *App) error {
 			// Do not block here
 		}
 
-		time.Sleep(1 * time.Minute)
-	}
-}
-
-func (crawl *Crawl) awaitTeardown() error {
-	const timeout = 2 * time.Hour
-	done := time.After(timeout)
-	for {
-		response, err := crawl.request(http.MethodGet, crawl.endpoint, nil)
-		if err != nil {
-			return err
-		}
-
-		if response.StatusCode != 200 {
-			err = fmt.Errorf("response returned code %s", response.Status)
-			return err
-		}
-
-		body, err := io.ReadAll(response.Body)
-		if err != nil {
-			return err
-		}
-
-		crawlResponse := new(CrawlResponse)
-
-		err = xml.Unmarshal(body, crawlResponse)
+		err = sleep(ctx, crawl.Job.pollInterval)
 		if err != nil {
 			return err
 		}
-
-		if crawlResponse.IsRunning == "false" &&
-		crawlResponse.IsLaunchable == "true" {
-			return nil
-		}
-
-		select {
-		case <-done:
-			{
-				err = fmt.Errorf("the crawl was not tear down before timeout, it is likely frozen and must be shut down by operator")
-				return err
-			}
-		default:
-			// Do not block here
-		}
 	}
 }
 
-type CrawlResponse struct {
-	XMLName         xml.Name `xml:"job"`
-	ControllerState string   `xml:"crawlControllerState"`
-	ExitStatus      string   `xml:"crawlExitStatus"`
-	ExitDescription string   `xml:"statusDescription"`
-	Actions         []string `xml:"availableActions>value"`
-	IsRunning       string   `xml:"isRunning"`
-	IsLaunchable    string   `xml:"isLaunchable"`
+// Requests checkpoint of stalled crawl when configured. Failed checkpoint
+// does not prevent terminating the crawl.
+func (crawl *Crawl) checkpointStalled(ctx context.Context, app *App) error {
+	if !crawl.Job.Stall.Checkpoint {
+		return nil
+	}
+	err := crawl.checkpoint(ctx, app)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		app.Log.Error(
+			"checkpoint of stalled crawl failed",
+			slog.String(ErrorKey, err.Error()),
+		)
+		return nil
+	}
+	app.Log.Info("checkpoint of stalled crawl requested")
+	return nil
 }
@@ -0,0 +1,74 @@
+package silence
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"strings"
+	"text/tabwriter"
+)
+
+// Returns path of crawler-beans rendered for the crawl by dry run. Every
+// crawl has its own file next to its seeds, so they can be compared.
+func (crawl *Crawl) dryRunBeansFile() string {
+	name := fmt.Sprintf("crawler-beans-%s-%03d.cxml", crawl.Timestamp, crawl.ID)
+	return path.Join(path.Dir(crawl.SeedsFile), name)
+}
+
+// Splits seeds and renders crawler-beans of every crawl like run does,
+// but never contacts Heritrix and does not save state. Summary of the
+// crawls is written to out.
+func (job *Job) dryRun(app *App, out io.Writer) error {
+	err := job.initCrawls(app)
+	if err != nil {
+		return err
+	}
+
+	for _, crawl := range job.crawls {
+		seedCount, err := countLines(crawl.SeedsFile)
+		if err != nil {
+			app.Log.Error(
+				fmt.Sprintf("failed to count seeds in %s", crawl.SeedsFile),
+				slog.String(ErrorKey, err.Error()),
+			)
+			return err
+		}
+		crawl.report.SeedCount = seedCount
+
+		beansFile := crawl.dryRunBeansFile()
+		err = crawl.createCrawlBeans(beansFile)
+		if err != nil {
+			app.Log.Error(
+				fmt.Sprintf("failed to create %s", beansFile),
+				slog.String(ErrorKey, err.Error()),
+			)
+			return err
+		}
+
+		app.Log.Debug(
+			fmt.Sprintf("crawl %d was rendered", crawl.ID),
+			slog.String("name", crawl.name),
+			slog.String("beans", beansFile),
+		)
+	}
+
+	return job.printDryRun(out)
+}
+
+func (job *Job) printDryRun(out io.Writer) error {
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tNAME\tSEEDS\tSEEDS FILE\tBEANS FILE\tWARC STORE PATHS")
+	for _, crawl := range job.crawls {
+		storePaths := strings.Join(crawl.warcStorePaths, ",")
+		if storePaths == "" {
+			storePaths = "-"
+		}
+		fmt.Fprintf(
+			writer, "%d\t%s\t%d\t%s\t%s\t%s\n",
+			crawl.ID, crawl.name, crawl.report.SeedCount,
+			crawl.SeedsFile, crawl.dryRunBeansFile(), storePaths,
+		)
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,353 @@
+package silence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Settings of Heritrix process started and stopped by silence.
+// When Home is empty, Heritrix is expected to be managed by operator.
+type HeritrixConfig struct {
+	// HERITRIX_HOME, working directory of the process
+	Home string
+	// JAVA_OPTS passed to the launch script
+	JavaOpts string
+	// Command starting Heritrix in foreground, relative paths are resolved
+	// against Home. Default is bin/heritrix with credentials and port of the
+	// job, credentials are passed in a file, so they are not seen in ps.
+	Command []string
+	// How long to wait for Heritrix to answer after start
+	StartupTimeoutSeconds int
+	// How long to wait for Heritrix to exit before it is killed
+	ShutdownTimeoutSeconds int
+	// File for stdout and stderr of the process, relative to working directory
+	LogFile string
+}
+
+func DefaultHeritrixConfig() *HeritrixConfig {
+	return &HeritrixConfig{
+		StartupTimeoutSeconds:  90,
+		ShutdownTimeoutSeconds: 60,
+		LogFile:                "heritrix.log",
+	}
+}
+
+func (hc *HeritrixConfig) managed() bool {
+	return hc != nil && hc.Home != ""
+}
+
+// Supervises Heritrix process started by silence. Start, stop and
+// restart may be called from several crawls, they are serialized.
+type supervisor struct {
+	job     *Job
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	logFile *os.File
+	exited  chan struct{}
+	waitErr error
+	// File with credentials for the default command, removed after start
+	credentials string
+}
+
+func newSupervisor(job *Job) *supervisor {
+	return &supervisor{job: job}
+}
+
+func (sv *supervisor) config() *HeritrixConfig {
+	return sv.job.Heritrix
+}
+
+// Command with arguments used to start Heritrix.
+func (sv *supervisor) command() ([]string, error) {
+	command := sv.config().Command
+	if len(command) == 0 {
+		endpoint := sv.job.pool[0]
+		address := endpoint.Address
+		if !strings.Contains(address, "://") {
+			address = "https://" + address
+		}
+		parsed, err := url.Parse(address)
+		if err != nil {
+			return nil, err
+		}
+		credentials, err := sv.writeCredentials(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		command = []string{
+			filepath.Join("bin", "heritrix"),
+			"-a", "@" + credentials,
+		}
+		if port := parsed.Port(); port != "" {
+			command = append(command, "-p", port)
+		}
+		if host := parsed.Hostname(); host != "" && !isLoopback(host) {
+			command = append(command, "-b", host)
+		}
+	}
+
+	// Relative path is resolved against Dir of the command, which is Home
+	return command, nil
+}
+
+// Writes credentials of the endpoint into a file readable only by the
+// owner, Heritrix reads them from it with "-a @file".
+func (sv *supervisor) writeCredentials(endpoint *CrawlerEndpoint) (string, error) {
+	file, err := os.CreateTemp("", "silence-heritrix-auth-*")
+	if err != nil {
+		return "", err
+	}
+	sv.credentials = file.Name()
+	_, err = file.WriteString(endpoint.Username + ":" + endpoint.Password)
+	closeErr := file.Close()
+	if err != nil || closeErr != nil {
+		sv.removeCredentials()
+		return "", errors.Join(err, closeErr)
+	}
+	return sv.credentials, nil
+}
+
+// Heritrix reads the credentials only at startup, the file is not needed
+// once it answers or fails to start.
+func (sv *supervisor) removeCredentials() {
+	if sv.credentials == "" {
+		return
+	}
+	os.Remove(sv.credentials)
+	sv.credentials = ""
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Starts Heritrix and waits until it answers. Refuses to start when
+// something already answers on the address.
+func (sv *supervisor) start(ctx context.Context, app *App) error {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	return sv.startLocked(ctx, app)
+}
+
+func (sv *supervisor) startLocked(ctx context.Context, app *App) error {
+	config := sv.config()
+
+	err := sv.ping(ctx)
+	if err == nil {
+		err = fmt.Errorf("heritrix is already running on %s", sv.job.client.Address())
+		app.Log.Error(
+			"heritrix must not be running before silence starts it",
+			slog.String(ErrorKey, err.Error()),
+		)
+		return err
+	}
+
+	command, err := sv.command()
+	defer sv.removeCredentials()
+	if err != nil {
+		return err
+	}
+	home, err := filepath.Abs(config.Home)
+	if err != nil {
+		return err
+	}
+
+	logFile, err := os.OpenFile(config.LogFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		app.Log.Error(
+			fmt.Sprintf("failed to open %s", config.LogFile),
+			slog.String(ErrorKey, err.Error()),
+		)
+		return err
+	}
+	sv.logFile = logFile
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Dir = home
+	cmd.Env = append(os.Environ(),
+		"HERITRIX_HOME="+home,
+		"JAVA_OPTS="+config.JavaOpts,
+		// Without it the launch script detaches java and exits
+		"FOREGROUND=true",
+	)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	// Own process group, so Ctrl-C in terminal does not reach Heritrix
+	// and it can be stopped in orderly fashion.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	app.Log.Info(
+		"starting heritrix",
+		slog.String("home", home),
+		slog.String("command", command[0]),
+		slog.String("log", config.LogFile),
+	)
+	err = cmd.Start()
+	if err != nil {
+		sv.logFile.Close()
+		app.Log.Error(
+			"failed to start heritrix",
+			slog.String(ErrorKey, err.Error()),
+		)
+		return err
+	}
+	sv.cmd = cmd
+	sv.exited = make(chan struct{})
+	go func() {
+		sv.waitErr = cmd.Wait()
+		sv.logFile.Close()
+		close(sv.exited)
+	}()
+
+	err = sv.awaitStartup(ctx, app)
+	if err != nil {
+		app.Log.Error(
+			"heritrix did not start",
+			slog.String("log", config.LogFile),
+			slog.String(ErrorKey, err.Error()),
+		)
+		sv.kill(app)
+		return err
+	}
+
+	app.Log.Info("heritrix is running", slog.Int("pid", cmd.Process.Pid))
+	return nil
+}
+
+func (sv *supervisor) awaitStartup(ctx context.Context, app *App) error {
+	timeout := time.Duration(sv.config().StartupTimeoutSeconds) * time.Second
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		err := sv.ping(ctx)
+		if err == nil {
+			return nil
+		}
+		app.Log.Debug("heritrix is not answering yet", slog.String(ErrorKey, err.Error()))
+
+		select {
+		case <-sv.exited:
+			return fmt.Errorf("heritrix exited during startup: %v", sv.waitErr)
+		case <-deadline:
+			return fmt.Errorf("heritrix did not answer in %s", timeout)
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (sv *supervisor) ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_, err := sv.job.client.Engine(ctx)
+	return err
+}
+
+// Stops Heritrix, first by asking the engine to exit, then by SIGTERM
+// and at last by SIGKILL.
+func (sv *supervisor) stop(app *App) error {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	return sv.stopLocked(app)
+}
+
+func (sv *supervisor) stopLocked(app *App) error {
+	if sv.cmd == nil {
+		return nil
+	}
+
+	timeout := time.Duration(sv.config().ShutdownTimeoutSeconds) * time.Second
+	app.Log.Info("stopping heritrix")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	err := sv.job.client.Exit(ctx)
+	cancel()
+	if err != nil && !sv.hasExited() {
+		app.Log.Warn(
+			"heritrix refused exit action",
+			slog.String(ErrorKey, err.Error()),
+		)
+	}
+	if sv.waitExit(timeout / 2) {
+		app.Log.Info("heritrix exited")
+		return nil
+	}
+
+	app.Log.Warn("heritrix did not exit, sending SIGTERM")
+	err = syscall.Kill(-sv.cmd.Process.Pid, syscall.SIGTERM)
+	if err != nil && !errors.Is(err, syscall.ESRCH) {
+		app.Log.Error("failed to send SIGTERM", slog.String(ErrorKey, err.Error()))
+	}
+	if sv.waitExit(timeout / 2) {
+		app.Log.Info("heritrix exited after SIGTERM")
+		return nil
+	}
+
+	return sv.kill(app)
+}
+
+// Stops Heritrix and starts it again. Jobs are forgotten by Heritrix,
+// their directories are kept.
+func (sv *supervisor) restart(ctx context.Context, app *App) error {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+
+	err := sv.stopLocked(app)
+	if err != nil {
+		return err
+	}
+	sv.cmd = nil
+	return sv.startLocked(ctx, app)
+}
+
+func (sv *supervisor) kill(app *App) error {
+	if sv.cmd == nil || sv.hasExited() {
+		return nil
+	}
+	app.Log.Warn("killing heritrix")
+	err := syscall.Kill(-sv.cmd.Process.Pid, syscall.SIGKILL)
+	if err != nil && !errors.Is(err, syscall.ESRCH) {
+		app.Log.Error("failed to kill heritrix", slog.String(ErrorKey, err.Error()))
+		return err
+	}
+	if !sv.waitExit(10 * time.Second) {
+		return fmt.Errorf("heritrix (pid %d) did not exit after SIGKILL", sv.cmd.Process.Pid)
+	}
+	return nil
+}
+
+func (sv *supervisor) hasExited() bool {
+	select {
+	case <-sv.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+func (sv *supervisor) waitExit(timeout time.Duration) bool {
+	select {
+	case <-sv.exited:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
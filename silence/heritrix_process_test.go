@@ -0,0 +1,85 @@
+package silence
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"silence/heritrix/heritrixtest"
+	"strings"
+	"testing"
+)
+
+func TestSupervisorRefusesRunningHeritrix(t *testing.T) {
+	server := heritrixtest.NewServer("admin", "secret")
+	defer server.Close()
+
+	app := newTestApp(t)
+	job := newTestJob(t, server, 1)
+	job.Heritrix.Home = t.TempDir()
+	job.Heritrix.Command = []string{"true"}
+
+	err := newSupervisor(job).start(context.Background(), app)
+	if err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Fatalf("expected already running error, got %v", err)
+	}
+}
+
+func TestSupervisorDetectsEarlyExit(t *testing.T) {
+	server := heritrixtest.NewServer("admin", "secret")
+	app := newTestApp(t)
+	job := newTestJob(t, server, 1)
+	// Nothing listens on the address anymore
+	server.Close()
+
+	job.Heritrix.Home = t.TempDir()
+	job.Heritrix.Command = []string{"sh", "-c", "echo starting; exit 3"}
+
+	sv := newSupervisor(job)
+	err := sv.start(context.Background(), app)
+	if err == nil || !strings.Contains(err.Error(), "exited during startup") {
+		t.Fatalf("expected early exit error, got %v", err)
+	}
+}
+
+func TestSupervisorPassesCredentialsInFile(t *testing.T) {
+	server := heritrixtest.NewServer("admin", "secret")
+	app := newTestApp(t)
+	job := newTestJob(t, server, 1)
+	server.Close()
+
+	// Launch script records its arguments and the credentials it was given
+	job.Heritrix.Home = t.TempDir()
+	script := filepath.Join(job.Heritrix.Home, "bin", "heritrix")
+	err := os.MkdirAll(filepath.Dir(script), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(script, []byte("#!/bin/sh\necho \"$@\" > args\ncat \"${2#@}\" > credentials\nexit 3\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sv := newSupervisor(job)
+	err = sv.start(context.Background(), app)
+	if err == nil || !strings.Contains(err.Error(), "exited during startup") {
+		t.Fatalf("expected early exit error, got %v", err)
+	}
+
+	args, err := os.ReadFile(filepath.Join(job.Heritrix.Home, "args"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(args), "secret") || !strings.HasPrefix(string(args), "-a @") {
+		t.Fatalf("credentials are not passed in file: %s", args)
+	}
+	credentials, err := os.ReadFile(filepath.Join(job.Heritrix.Home, "credentials"))
+	if err != nil || string(credentials) != "admin:secret" {
+		t.Fatalf("unexpected credentials %q: %v", credentials, err)
+	}
+	path := strings.TrimPrefix(strings.Fields(string(args))[1], "@")
+	_, err = os.Stat(path)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("credentials file %s was not removed: %v", path, err)
+	}
+}
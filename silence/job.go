@@ -2,18 +2,17 @@ package silence
 
 import (
 	"bufio"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"io"
 	"io/fs"
 	"log/slog"
-	"math"
-	"net/http"
 	"os"
+	"path"
+	"silence/heritrix"
+	"sync"
+	"text/template"
 	"time"
-
-	"github.com/icholy/digest"
 )
 
 type Job struct {
@@ -25,13 +24,37 @@ type Job struct {
 	CrawlerAddress  string
 	CrawlerUsername string
 	CrawlerPassword string
-	MaxLines        int
-	MaxIterations   int
-	MaxWaitSeconds  int
-	Config          *JobConfig
-
-	client *http.Client
-	crawls []*Crawl
+	// Jobs directory of Heritrix at CrawlerAddress, see CrawlerEndpoint.JobsDir
+	JobsDir string
+	// Heritrix instances, when empty CrawlerAddress and credentials are used
+	Crawlers       []*CrawlerEndpoint
+	MaxLines       int
+	MaxIterations  int
+	MaxWaitSeconds int
+	SplitStrategy  SplitStrategy
+	Config         *JobConfig
+	Heritrix       *HeritrixConfig
+	Stall          *StallConfig
+	Retry          *RetryConfig
+	Wait           *WaitConfig
+	Teardown       *TeardownConfig
+	PartJobs       *PartJobsConfig
+
+	// Client of the first endpoint, supervisor starts Heritrix there
+	client     *heritrix.Client
+	pool       []*CrawlerEndpoint
+	supervisor *supervisor
+	crawls     []*Crawl
+	metrics    *metrics
+	// Start of the run, names files kept next to its report
+	started time.Time
+	// Template parsed once, see Job.template
+	templateOnce  sync.Once
+	beansTemplate *template.Template
+	templateErr   error
+
+	// Delay between status checks of running crawl
+	pollInterval time.Duration
 }
 
 const DefaultJobConfigPath = "job.json"
@@ -40,6 +63,7 @@ const SeedsDirectory = "seeds_dir"
 
 const CrawlerBeansName = "crawler-beans.cxml"
 
+// Loads job config from path and validates it.
 func NewJob(app *App, path string) (*Job, error) {
 	job := DefaultJob(path)
 
@@ -52,7 +76,7 @@ func NewJob(app *App, path string) (*Job, error) {
 		return job, err
 	}
 
-	err = json.Unmarshal(data, job)
+	err = unmarshalJob(job.configPath, data, job)
 	if err != nil {
 		app.Log.Error(
 			"failed to unmarshal job file",
@@ -61,16 +85,21 @@ func NewJob(app *App, path string) (*Job, error) {
 		return job, err
 	}
 
-	if job.TemplatePath == CrawlerBeansName {
-		err = fmt.Errorf("%s is invalid name for template", job.TemplatePath)
+	err = job.Validate()
+	if err != nil {
+		logValidation(app, err)
+		return job, err
+	}
+
+	err = job.initClient(app)
+	if err != nil {
 		app.Log.Error(
-			fmt.Sprintf("template connot be named %s", CrawlerBeansName),
+			"failed to create heritrix client",
 			slog.String(ErrorKey, err.Error()),
 		)
+		return job, err
 	}
 
-	job.initClient()
-
 	return job, nil
 }
 
@@ -82,99 +111,146 @@ func DefaultJob(path string) *Job {
 		CrawlerAddress: "localhost:7778",
 		MaxIterations:  20,
 		MaxLines:       64_000,
+		SplitStrategy:  SplitLines,
 		Config:         new(JobConfig),
+		Heritrix:       DefaultHeritrixConfig(),
+		Stall:          new(StallConfig),
+		Retry:          DefaultRetryConfig(),
+		Wait:           DefaultWaitConfig(),
+		Teardown:       DefaultTeardownConfig(),
+		PartJobs:       DefaultPartJobsConfig(),
+		metrics:        newMetrics(),
+
+		pollInterval: 1 * time.Minute,
 	}
 }
 
-func (job *Job) initClient() {
-	// Heritrix uses self signed certificates, this is fine for now
-	heritrixTransport := http.DefaultTransport.(*http.Transport).Clone()
-	heritrixTransport.TLSClientConfig.InsecureSkipVerify = true
-	digestTransport := &digest.Transport{
-		Username:  job.CrawlerUsername,
-		Password:  job.CrawlerPassword,
-		Transport: heritrixTransport,
-	}
-	job.client = &http.Client{Transport: digestTransport}
+func (job *Job) initClient(app *App) error {
+	return job.initPool(app)
 }
 
-func (job *Job) run(app *App) error {
-	err := job.initCrawls(app)
+func (job *Job) run(ctx context.Context, app *App) error {
+	exists, err := stateExists()
 	if err != nil {
 		return err
 	}
+	if exists {
+		err = fmt.Errorf("%s already exists", StateFileName)
+		app.Log.Error(
+			"state of unfinished job found, use resume command or remove the file",
+			slog.String(ErrorKey, err.Error()),
+		)
+		return err
+	}
 
-	err = job.runCrawls(app)
+	err = job.initCrawls(app)
 	if err != nil {
 		return err
 	}
 
-	return nil
-}
-
-func (job *Job) initCrawls(app *App) error {
-	if job.MaxIterations < 1 {
-		err := fmt.Errorf("MaxIterations is set to less than one")
+	err = job.saveState()
+	if err != nil {
 		app.Log.Error(
-			"MaxIterations must be bigger than 0",
+			fmt.Sprintf("failed to save state to %s", StateFileName),
 			slog.String(ErrorKey, err.Error()),
 		)
 		return err
 	}
 
-	lines, err := countLines(job.SeedsPath)
+	err = job.runCrawls(ctx, app)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Splits seeds into crawls. The job must be valid, see Validate.
+func (job *Job) initCrawls(app *App) error {
+	seeds, err := readSeeds(job.SeedsPath)
 	if err != nil {
 		app.Log.Error(
-			fmt.Sprintf("failed to get count of lines in file %s", job.SeedsPath),
+			fmt.Sprintf("failed to read seeds from file %s", job.SeedsPath),
 			slog.String(ErrorKey, err.Error()),
-			slog.Int("lines", lines),
 		)
 		return err
 	}
 
-	if job.MaxLines < 1 {
-		err := fmt.Errorf("MaxLines is set to less than one")
+	// Create seeds directory
+	err = os.Mkdir(SeedsDirectory, 0755)
+	if err != nil && !errors.Is(err, fs.ErrExist) {
 		app.Log.Error(
-			"MaxLines must be bigger than 0",
+			fmt.Sprintf("failed to create directory %s", SeedsDirectory),
 			slog.String(ErrorKey, err.Error()),
 		)
 		return err
 	}
-	iterations := int(math.Ceil(float64(lines) / float64(job.MaxLines)))
-	if iterations > job.MaxIterations {
-		err := fmt.Errorf("too many iterations needed")
+
+	const timestampFormat = "20060102150405"
+	timestamp := time.Now().Format(timestampFormat)
+
+	seeds, rejects, counts := preprocessSeeds(seeds)
+	app.Log.Info(
+		"seeds preprocessed",
+		slog.Int("lines", counts.Lines),
+		slog.Int("skipped", counts.Skipped),
+		slog.Int("rejected", counts.Rejected),
+		slog.Int("duplicates", counts.Duplicates),
+		slog.Int("accepted", counts.Accepted),
+	)
+	if len(rejects) > 0 {
+		rejectsPath := path.Join(SeedsDirectory, fmt.Sprintf("seeds-%s-rejected.txt", timestamp))
+		err = writeRejectedSeeds(rejectsPath, rejects)
+		if err != nil {
+			app.Log.Error(
+				fmt.Sprintf("failed to write rejected seeds to %s", rejectsPath),
+				slog.String(ErrorKey, err.Error()),
+			)
+			return err
+		}
+		app.Log.Warn(
+			fmt.Sprintf("%d seeds were rejected, see %s", len(rejects), rejectsPath),
+		)
+	}
+	if len(seeds) == 0 {
+		err = fmt.Errorf("no valid seeds in %s", job.SeedsPath)
 		app.Log.Error(
-			fmt.Sprintf("number of iterations (%d) is bigger than max_iterations (%d)", iterations, job.MaxIterations),
+			"nothing to crawl",
 			slog.String(ErrorKey, err.Error()),
 		)
 		return err
 	}
 
-	// Create seeds directory
-	err = os.Mkdir(SeedsDirectory, 0755)
-	if err != nil && !errors.Is(err, fs.ErrExist) {
+	parts := splitSeeds(seeds, job.MaxLines, job.SplitStrategy)
+	iterations := len(parts)
+	if iterations > job.MaxIterations {
+		err := fmt.Errorf("too many iterations needed")
 		app.Log.Error(
-			fmt.Sprintf("failed to create directory %s", SeedsDirectory),
+			fmt.Sprintf("number of iterations (%d) is bigger than max_iterations (%d)", iterations, job.MaxIterations),
 			slog.String(ErrorKey, err.Error()),
 		)
 		return err
 	}
 
 	crawls := make([]*Crawl, 0, iterations)
-	const timestampFormat = "20060102150405"
-	timestamp := time.Now().Format(timestampFormat)
 	for i := 0; i < cap(crawls); i++ {
 		crawls = append(crawls, NewCrawl(i, timestamp, SeedsDirectory, job))
 	}
 
-	app.Log.Debug("", slog.Int("lines", lines), slog.Int("iterations", len(crawls)))
+	app.Log.Debug(
+		"",
+		slog.Int("seeds", len(seeds)),
+		slog.Int("iterations", len(crawls)),
+		slog.String("split", string(job.SplitStrategy)),
+	)
 
-	err = job.createSeedFiles(crawls)
+	err = writeSeedFiles(crawls, parts)
 	if err != nil {
 		app.Log.Error(
 			"failed to create seed files for individual harvests",
 			slog.String(ErrorKey, err.Error()),
 		)
+		return err
 	}
 
 	job.crawls = crawls
@@ -206,79 +282,103 @@ func countLines(path string) (int, error) {
 	return sum, nil
 }
 
-func (job *Job) createSeedFiles(crawls []*Crawl) error {
-	linesPerFile := job.MaxLines
-	seedsOrigin, err := os.Open(job.SeedsPath)
+func (job *Job) stopHeritrix(app *App) {
+	err := job.supervisor.stop(app)
+	if err != nil {
+		app.Log.Error(
+			"failed to stop heritrix, it must be stopped by operator",
+			slog.String(ErrorKey, err.Error()),
+		)
+	}
+}
+
+// Loads crawls saved by interrupted run and runs those that did not finish.
+func (job *Job) resume(ctx context.Context, app *App) error {
+	state, err := job.loadState()
 	if err != nil {
+		app.Log.Error(
+			fmt.Sprintf("failed to load state from %s", StateFileName),
+			slog.String(ErrorKey, err.Error()),
+		)
 		return err
 	}
-	defer seedsOrigin.Close()
 
-	seedsScanner := bufio.NewScanner(seedsOrigin)
+	if state.Job != job.Name {
+		err = fmt.Errorf("state belongs to job %s, not %s", state.Job, job.Name)
+		app.Log.Error(
+			"saved state does not match the job",
+			slog.String(ErrorKey, err.Error()),
+		)
+		return err
+	}
 
-	for _, crawl := range crawls {
-		seedsBatch, err := os.Create(crawl.SeedsFile)
-		if err != nil {
-			err = fmt.Errorf("failed to create file %s with error: %w", crawl.SeedsFile, err)
-			return err
+	first := -1
+	for i, crawl := range state.Crawls {
+		crawl.attach(job)
+		if crawl.Status == CrawlFinished {
+			continue
 		}
-		defer seedsBatch.Close()
-
-		// Only owner and group can write - or not
-		err = seedsBatch.Chmod(0666)
-		if err != nil {
-			err = fmt.Errorf("failed to change permissions to file %s with error: %w", crawl.SeedsFile, err)
-			return err
+		if first == -1 {
+			first = i
 		}
 
-		err = copyLines(seedsScanner, seedsBatch, linesPerFile)
+		// Crawl that was running or failed is started again from the beginning
+		crawl.Status = CrawlQueued
+		_, err = os.Stat(crawl.SeedsFile)
 		if err != nil {
-			err = fmt.Errorf("failed to copyLines from %s to %s with error: %w", job.SeedsPath, crawl.SeedsFile, err)
+			app.Log.Error(
+				fmt.Sprintf("seeds file of crawl %d is not accessible", crawl.ID),
+				slog.String(ErrorKey, err.Error()),
+			)
 			return err
 		}
 	}
+	job.crawls = state.Crawls
 
-	return nil
+	if first == -1 {
+		app.Log.Info("all crawls are finished, nothing to resume")
+		return job.removeState()
+	}
+
+	app.Log.Info(
+		fmt.Sprintf("resuming from crawl %d", state.Crawls[first].ID),
+		slog.Int("remaining", len(state.Crawls)-first),
+		slog.Int("total", len(state.Crawls)),
+	)
+
+	return job.runCrawls(ctx, app)
 }
 
-func copyLines(linesIn *bufio.Scanner, linesOut io.Writer, linesPerFile int) error {
-	writer := bufio.NewWriter(linesOut)
-	i := 0
-	// Scan until linesPerFile, scanner hits EOF or scanner encouters error.
-	for i < linesPerFile && linesIn.Scan() {
-		line := linesIn.Bytes()
-		_, err := writer.Write(line)
-		if err != nil {
-			return err
-		}
-		err = writer.WriteByte('\n')
+func (job *Job) runCrawls(ctx context.Context, app *App) (err error) {
+	started := time.Now()
+	job.started = started
+	defer func() {
+		job.writeReport(app, started, ctx.Err() != nil, err)
+	}()
+
+	if job.Heritrix.managed() {
+		job.supervisor = newSupervisor(job)
+		err = job.supervisor.start(ctx, app)
 		if err != nil {
 			return err
 		}
-		i++
+		defer job.stopHeritrix(app)
 	}
-	if linesIn.Err() != nil {
-		return linesIn.Err()
+
+	job.metrics.setParts(len(job.crawls))
+	err = job.schedule(ctx, app)
+	if err != nil {
+		return err
 	}
-	return writer.Flush()
-}
 
-func (job *Job) runCrawls(app *App) error {
-	for _, crawl := range job.crawls {
-		app.Log.Info(
-			fmt.Sprintf("starting crawl %d", crawl.ID),
+	err = job.removeState()
+	if err != nil {
+		app.Log.Error(
+			fmt.Sprintf("failed to remove %s", StateFileName),
+			slog.String(ErrorKey, err.Error()),
 		)
-		err := crawl.Run(app)
-		if err != nil {
-			app.Log.Error(
-				fmt.Sprintf("error when processing crawl %d", crawl.ID),
-				slog.String(ErrorKey, err.Error()),
-				slog.Int("id", crawl.ID),
-			)
-			return err
-		}
+		return err
 	}
 
-	// ---
 	return nil
 }
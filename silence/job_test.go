@@ -0,0 +1,758 @@
+package silence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"silence/heritrix"
+	"silence/heritrix/heritrixtest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testJobName = "topics"
+
+type testLogWriter struct {
+	t *testing.T
+}
+
+func (w testLogWriter) Write(p []byte) (int, error) {
+	w.t.Log(strings.TrimSpace(string(p)))
+	return len(p), nil
+}
+
+func newTestApp(t *testing.T) *App {
+	app := new(App)
+	app.initLogger(testLogWriter{t}, slog.LevelDebug)
+	return app
+}
+
+// Changes working directory to new temporary directory for the duration of the test.
+func chdirTemp(t *testing.T) string {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chdir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+func writeTestFile(t *testing.T, name string, content string) {
+	err := os.WriteFile(name, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Smallest crawler-beans template with all required beans
+const testTemplate = `<?xml version="1.0" encoding="UTF-8"?>
+<beans>
+ <bean id="simpleOverrides">
+  <property name="properties">
+   <value>
+name={{.CrawlName}}
+seeds={{.SeedsFile}}
+   </value>
+  </property>
+ </bean>
+ <bean id="metadata"/>
+ <bean id="seeds"/>
+ <bean id="warcWriter"/>
+ <bean id="crawlLimiter"/>
+</beans>
+`
+
+// Prepares working directory with seeds and template and job pointed at the server.
+func newTestJob(t *testing.T, server *heritrixtest.Server, seeds int) *Job {
+	chdirTemp(t)
+
+	var lines []string
+	for i := 0; i < seeds; i++ {
+		lines = append(lines, "https://example.com/"+string(rune('a'+i)))
+	}
+	writeTestFile(t, "seeds.txt", strings.Join(lines, "\n")+"\n")
+	writeTestFile(t, "crawler-beans.template", testTemplate)
+
+	job := DefaultJob(DefaultJobConfigPath)
+	job.Name = testJobName
+	job.CrawlerAddress = server.URL
+	job.CrawlerUsername = server.Username
+	job.CrawlerPassword = server.Password
+	job.MaxLines = 2
+	job.MaxWaitSeconds = 60
+	job.Config.Operator = "tester"
+	job.Config.DedupDir = "dedup"
+	job.Config.ToeThreads = 4
+	job.Wait.IntervalMillis = 1
+	job.pollInterval = time.Millisecond
+
+	job.Retry.InitialDelayMillis = 1
+	job.JobsDir = "jobs"
+	err := os.MkdirAll(filepath.Join(job.JobsDir, testJobName), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = job.initClient(newTestApp(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func expectedActions(crawls int) []string {
+	var actions []string
+	for i := 0; i < crawls; i++ {
+		actions = append(actions, "build", "launch", "unpause", "terminate", "teardown")
+	}
+	return actions
+}
+
+func TestJobRun(t *testing.T) {
+	server := heritrixtest.NewServer("admin", "secret")
+	defer server.Close()
+	server.JobsDir = t.TempDir()
+	server.AddJob(testJobName)
+
+	app := newTestApp(t)
+	job := newTestJob(t, server, 5)
+
+	err := job.run(context.Background(), app)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(job.crawls) != 3 {
+		t.Fatalf("expected 3 crawls, got %d", len(job.crawls))
+	}
+	for _, crawl := range job.crawls {
+		if crawl.Status != CrawlFinished {
+			t.Errorf("crawl %d has status %s", crawl.ID, crawl.Status)
+		}
+		_, err = os.Stat(crawl.SeedsFile)
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("seeds file %s was not removed: %v", crawl.SeedsFile, err)
+		}
+	}
+
+	actions := server.Actions(testJobName)
+	if !slices.Equal(actions, expectedActions(3)) {
+		t.Fatalf("got actions %v", actions)
+	}
+
+	_, err = os.Stat(StateFileName)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("state file was not removed after successful run: %v", err)
+	}
+
+	archives, err := filepath.Glob(filepath.Join(LogArchiveDirectory, "*.tar.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archives) != 3 {
+		t.Fatalf("expected archive of logs for each crawl, got %v", archives)
+	}
+	remaining, err := listLogs(filepath.Join(server.JobsDir, testJobName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 0 {
+		t.Fatalf("logs remain in job directory: %v", remaining)
+	}
+
+	report := readTestReport(t)
+	if report.Status != ReportFinished || len(report.Crawls) != 3 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	seeds := 0
+	for _, crawl := range report.Crawls {
+		seeds += crawl.SeedCount
+		if crawl.ControllerState == "" || !crawl.Cleanup.Done || crawl.Cleanup.LogArchive == "" {
+			t.Errorf("incomplete report of crawl %d: %+v", crawl.ID, crawl)
+		}
+		beans, err := os.ReadFile(crawl.BeansCopy)
+		if err != nil || !strings.Contains(string(beans), crawl.SeedsFile) {
+			t.Errorf("copy of crawler-beans of crawl %d is missing: %v", crawl.ID, err)
+		}
+	}
+	_, err = os.Stat(filepath.Join(job.JobsDir, testJobName, CrawlerBeansName))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("crawler-beans were not removed from job directory: %v", err)
+	}
+	if seeds != 5 {
+		t.Fatalf("report has %d seeds, expected 5", seeds)
+	}
+}
+
+func readTestReport(t *testing.T) *JobReport {
+	reports, err := filepath.Glob("report-*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("expected one report, got %v", reports)
+	}
+	data, err := os.ReadFile(reports[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := new(JobReport)
+	err = json.Unmarshal(data, report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func TestJobResume(t *testing.T) {
+	server := heritrixtest.NewServer("admin", "secret")
+	defer server.Close()
+	server.AddJob(testJobName)
+
+	app := newTestApp(t)
+	job := newTestJob(t, server, 5)
+
+	// Build of the first crawl fails even after retries
+	server.Fail(http.MethodPost, heritrix.JobPath(testJobName), http.StatusInternalServerError, job.Retry.Attempts)
+
+	err := job.run(context.Background(), app)
+	if err == nil {
+		t.Fatal("expected run to fail")
+	}
+
+	state, err := job.loadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Crawls[0].Status != CrawlFailed {
+		t.Fatalf("expected first crawl to fail, got %s", state.Crawls[0].Status)
+	}
+
+	err = job.run(context.Background(), app)
+	if err == nil {
+		t.Fatal("run must refuse to start over saved state")
+	}
+
+	err = job.resume(context.Background(), app)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actions := server.Actions(testJobName)
+	if !slices.Equal(actions[job.Retry.Attempts:], expectedActions(3)) {
+		t.Fatalf("got actions %v", actions)
+	}
+	_, err = os.Stat(StateFileName)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("state file was not removed after successful resume: %v", err)
+	}
+}
+
+func TestJobResumeMissingSeeds(t *testing.T) {
+	server := heritrixtest.NewServer("admin", "secret")
+	defer server.Close()
+	server.AddJob(testJobName)
+
+	app := newTestApp(t)
+	job := newTestJob(t, server, 3)
+
+	err := job.initCrawls(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = job.saveState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Remove(filepath.Clean(job.crawls[1].SeedsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = job.resume(context.Background(), app)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected missing seeds file error, got %v", err)
+	}
+	if len(server.Requests()) != 0 {
+		t.Fatal("resume contacted heritrix before verifying seeds")
+	}
+}
+
+func TestJobInterrupt(t *testing.T) {
+	server := heritrixtest.NewServer("admin", "secret")
+	defer server.Close()
+	server.JobsDir = t.TempDir()
+	// Crawl never finishes by itself
+	server.AddJob(testJobName, heritrix.StatePreparing, heritrix.StateRunning)
+
+	app := newTestApp(t)
+	job := newTestJob(t, server, 3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		for !slices.Contains(server.Actions(testJobName), "unpause") {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+
+	err := job.run(ctx, app)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled error, got %v", err)
+	}
+
+	actions := server.Actions(testJobName)
+	if !slices.Equal(actions, expectedActions(1)) {
+		t.Fatalf("got actions %v", actions)
+	}
+
+	state, err := job.loadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, crawl := range state.Crawls {
+		if crawl.Status != CrawlQueued {
+			t.Errorf("crawl %d has status %s", crawl.ID, crawl.Status)
+		}
+		_, err = os.Stat(crawl.SeedsFile)
+		if err != nil {
+			t.Errorf("seeds of interrupted crawl must be kept: %v", err)
+		}
+	}
+}
+
+// Reports whether the job got at least checks status requests after build.
+func checkedAfterBuild(server *heritrixtest.Server, checks int) bool {
+	built := false
+	for _, request := range server.Requests() {
+		if request.Path != heritrix.JobPath(testJobName) {
+			continue
+		}
+		if request.Action == "build" {
+			built = true
+		} else if built && request.Method == http.MethodGet {
+			checks--
+		}
+	}
+	return built && checks <= 0
+}
+
+func TestJobInterruptBeforeLaunch(t *testing.T) {
+	tests := []struct {
+		name string
+		// Status checks after build before interrupt, the first one still
+		// reports unbuilt job and completes the build, so the interrupt
+		// finds the job NASCENT
+		checks int
+	}{
+		{"during build", 0},
+		{"built job", 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := heritrixtest.NewServer("admin", "secret")
+			defer server.Close()
+			server.AddJob(testJobName)
+			server.SetLatency(20 * time.Millisecond)
+
+			app := newTestApp(t)
+			job := newTestJob(t, server, 2)
+			// Waiting for FINISHED would end with StuckError after this
+			job.Wait.TimeoutSeconds = 5
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				for !checkedAfterBuild(server, test.checks) {
+					time.Sleep(time.Millisecond)
+				}
+				cancel()
+			}()
+
+			started := time.Now()
+			err := job.run(ctx, app)
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("expected canceled error, got %v", err)
+			}
+			var stuck *StuckError
+			if errors.As(err, &stuck) || time.Since(started) > 4*time.Second {
+				t.Fatalf("interrupt waited for job that was not launched: %v", err)
+			}
+
+			actions := server.Actions(testJobName)
+			if !slices.Equal(actions, []string{"build", "teardown"}) {
+				t.Fatalf("got actions %v", actions)
+			}
+			if job.crawls[0].Status != CrawlQueued {
+				t.Fatalf("crawl has status %s", job.crawls[0].Status)
+			}
+		})
+	}
+}
+
+func TestProgressAttrs(t *testing.T) {
+	status := &heritrix.Job{
+		Threads:  &heritrix.ThreadReport{ToeCount: 50},
+		Frontier: &heritrix.FrontierReport{FailedFetchCount: 31, DisregardedURICount: 204},
+	}
+	record := slog.NewRecord(time.Time{}, slog.LevelInfo, "", 0)
+	record.Add(progressAttrs(status)...)
+	attrs := make(map[string]string)
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs[attr.Key] = attr.Value.String()
+		return true
+	})
+	expected := map[string]string{"failed": "31", "disregarded": "204", "toes": "50"}
+	if !maps.Equal(attrs, expected) {
+		t.Fatalf("got attributes %v, expected %v", attrs, expected)
+	}
+}
+
+func TestJobDryRun(t *testing.T) {
+	server := heritrixtest.NewServer("admin", "secret")
+	defer server.Close()
+
+	app := newTestApp(t)
+	job := newTestJob(t, server, 5)
+
+	var out strings.Builder
+	err := job.dryRun(app, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if requests := server.Requests(); len(requests) != 0 {
+		t.Fatalf("dry run contacted heritrix: %v", requests)
+	}
+	_, err = os.Stat(StateFileName)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("dry run saved state: %v", err)
+	}
+
+	if len(job.crawls) != 3 {
+		t.Fatalf("expected 3 crawls, got %d", len(job.crawls))
+	}
+	for _, crawl := range job.crawls {
+		data, err := os.ReadFile(crawl.dryRunBeansFile())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), "seeds="+crawl.SeedsFile) {
+			t.Errorf("beans of crawl %d do not use its seeds: %s", crawl.ID, data)
+		}
+		if !strings.Contains(out.String(), crawl.name) {
+			t.Errorf("name of crawl %d is missing in output:\n%s", crawl.ID, out.String())
+		}
+	}
+}
+
+func TestJobAbandonsUnreachableHeritrix(t *testing.T) {
+	server := heritrixtest.NewServer("admin", "secret")
+	defer server.Close()
+	server.AddJob(testJobName)
+
+	app := newTestApp(t)
+	job := newTestJob(t, server, 2)
+
+	// Pre-flight check and waits after build (2 checks), launch (2) and
+	// unpause (1) succeed, every attempt of the first status check during
+	// the crawl fails
+	server.FailAfter(http.MethodGet, heritrix.JobPath(testJobName), http.StatusServiceUnavailable, 6, job.Retry.Attempts)
+
+	err := job.run(context.Background(), app)
+	var retryErr *heritrix.RetryError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("expected retries to run out, got %v", err)
+	}
+
+	actions := server.Actions(testJobName)
+	if actions[len(actions)-1] != "terminate" {
+		t.Fatalf("abandoned crawl was not terminated: %v", actions)
+	}
+	if !server.ExitRequested() {
+		t.Fatal("heritrix was not asked to exit")
+	}
+	if job.crawls[0].Status != CrawlFailed {
+		t.Fatalf("crawl has status %s", job.crawls[0].Status)
+	}
+}
+
+func TestJobStopsCrawlOnClientError(t *testing.T) {
+	server := heritrixtest.NewServer("admin", "secret")
+	defer server.Close()
+	server.AddJob(testJobName)
+
+	app := newTestApp(t)
+	job := newTestJob(t, server, 2)
+
+	// Client errors are not retried and mean Heritrix still answers
+	server.FailAfter(http.MethodGet, heritrix.JobPath(testJobName), http.StatusNotFound, 6, 1)
+
+	err := job.run(context.Background(), app)
+	var statusErr *heritrix.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+	if server.ExitRequested() {
+		t.Fatal("heritrix that answers was asked to exit")
+	}
+	actions := server.Actions(testJobName)
+	if !slices.Equal(actions, expectedActions(1)) {
+		t.Fatalf("crawl was not stopped normally: %v", actions)
+	}
+}
+
+func TestJobStuckAfterLaunch(t *testing.T) {
+	server := heritrixtest.NewServer("admin", "secret")
+	defer server.Close()
+	server.AddJob(testJobName, heritrix.StatePreparing)
+
+	app := newTestApp(t)
+	job := newTestJob(t, server, 2)
+	job.Wait.Timeouts = map[string]int{"launch": 1}
+
+	err := job.run(context.Background(), app)
+	var stuck *StuckError
+	if !errors.As(err, &stuck) {
+		t.Fatalf("expected stuck error, got %v", err)
+	}
+	if stuck.Action != "launch" || stuck.State != heritrix.StatePreparing {
+		t.Fatalf("unexpected stuck error %+v", stuck)
+	}
+}
+
+func TestJobTeardownTimeout(t *testing.T) {
+	server := heritrixtest.NewServer("admin", "secret")
+	defer server.Close()
+	server.AddJob(testJobName)
+	server.SetStuckTeardown(testJobName, true)
+
+	app := newTestApp(t)
+	job := newTestJob(t, server, 2)
+	job.Teardown.TimeoutSeconds = 1
+	job.Teardown.IntervalMillis = 100
+
+	err := job.run(context.Background(), app)
+	var timeoutErr *TeardownTimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected teardown timeout, got %v", err)
+	}
+	if timeoutErr.State != heritrix.StateFinished {
+		t.Fatalf("unexpected state in %+v", timeoutErr)
+	}
+
+	// Without supervisor there is nothing to restart
+	job.Teardown.RestartHeritrix = true
+	err = job.crawls[0].escalateTeardown(context.Background(), app, timeoutErr)
+	if err != timeoutErr {
+		t.Fatalf("expected the timeout error back, got %v", err)
+	}
+
+	// Other crawl running on the same instance would be killed by restart
+	job.supervisor = newSupervisor(job)
+	endpoint := job.crawls[0].slot.endpoint
+	endpoint.acquire()
+	endpoint.acquire()
+	err = job.crawls[0].escalateTeardown(context.Background(), app, timeoutErr)
+	if err != timeoutErr {
+		t.Fatalf("expected the timeout error back, got %v", err)
+	}
+	if endpoint.restarting {
+		t.Fatal("instance is still reserved for restart")
+	}
+}
+
+func TestJobPool(t *testing.T) {
+	server := heritrixtest.NewServer("admin", "secret")
+	defer server.Close()
+	server.JobsDir = t.TempDir()
+	names := []string{testJobName, testJobName + "-1"}
+	for _, name := range names {
+		server.AddJob(name)
+		err := os.MkdirAll(filepath.Join(server.JobsDir, name), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	down := heritrixtest.NewServer("admin", "secret")
+	down.Close()
+
+	app := newTestApp(t)
+	job := newTestJob(t, server, 6)
+	job.Crawlers = []*CrawlerEndpoint{
+		{Address: server.URL, Username: server.Username, Password: server.Password, Concurrency: 2},
+		{Address: down.URL, Username: down.Username, Password: down.Password},
+	}
+	err := job.initClient(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = job.run(context.Background(), app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, crawl := range job.crawls {
+		if crawl.Status != CrawlFinished {
+			t.Fatalf("crawl %d has status %s", crawl.ID, crawl.Status)
+		}
+	}
+	if !job.Crawlers[1].down {
+		t.Fatal("closed heritrix was not marked as down")
+	}
+
+	// Both jobs of the running instance were used
+	launches := 0
+	for _, name := range names {
+		actions := server.Actions(name)
+		if len(actions) == 0 {
+			t.Fatalf("job %s was not used", name)
+		}
+		launches += len(actions) / len(expectedActions(1))
+		for _, config := range server.BuiltConfigs(name) {
+			ours := slices.ContainsFunc(job.crawls, func(crawl *Crawl) bool {
+				return strings.Contains(string(config), crawl.SeedsFile)
+			})
+			if !ours {
+				t.Fatalf("job %s was built without beans of any crawl: %q", name, config)
+			}
+		}
+		if len(server.BuiltConfigs(name)) != len(actions)/len(expectedActions(1)) {
+			t.Fatalf("beans were not written into job %s before each build", name)
+		}
+	}
+	if launches != len(job.crawls) {
+		t.Fatalf("expected %d crawls on the instance, got %d", len(job.crawls), launches)
+	}
+	expectedName := server.URL + "/" + strings.Join(names, ",")
+	if job.Crawlers[0].String() != expectedName {
+		t.Fatalf("expected endpoint %s, got %s", expectedName, job.Crawlers[0])
+	}
+
+	// Progress of parallel crawls is kept per part
+	var metrics strings.Builder
+	err = job.metrics.write(&metrics, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, crawl := range job.crawls {
+		line := fmt.Sprintf(`silence_heritrix_controller_state{part="%d",state="FINISHED"} 1`, crawl.ID)
+		if !strings.Contains(metrics.String(), line+"\n") {
+			t.Fatalf("metrics do not contain %q:\n%s", line, metrics.String())
+		}
+	}
+}
+
+func TestJobPartJobs(t *testing.T) {
+	for _, method := range []PartJobsMethod{PartJobsCreate, PartJobsAdd} {
+		t.Run(string(method), func(t *testing.T) {
+			server := heritrixtest.NewServer("admin", "secret")
+			defer server.Close()
+			server.JobsDir = t.TempDir()
+
+			app := newTestApp(t)
+			job := newTestJob(t, server, 4)
+			job.PartJobs.Enabled = true
+			job.PartJobs.Method = method
+			job.PartJobs.Dir = t.TempDir()
+
+			err := job.run(context.Background(), app)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			report := readTestReport(t)
+			dirs := make(map[string]bool)
+			for i, crawl := range job.crawls {
+				if crawl.HeritrixJob == "" || server.Job(crawl.HeritrixJob) == nil {
+					t.Fatalf("crawl %d has no heritrix job", crawl.ID)
+				}
+				// Other run on the same day must not use the same job
+				if !strings.HasSuffix(crawl.HeritrixJob, "-"+crawl.Timestamp) {
+					t.Fatalf("heritrix job %s has no timestamp of the run", crawl.HeritrixJob)
+				}
+				if !slices.Equal(server.Actions(crawl.HeritrixJob), expectedActions(1)) {
+					t.Fatalf("unexpected actions of %s: %v", crawl.HeritrixJob, server.Actions(crawl.HeritrixJob))
+				}
+				jobDir := report.Crawls[i].JobDir
+				if jobDir == "" || dirs[jobDir] {
+					t.Fatalf("job directory %q is not dedicated", jobDir)
+				}
+				dirs[jobDir] = true
+				built := server.BuiltConfigs(crawl.HeritrixJob)
+				if len(built) != 1 || !strings.Contains(string(built[0]), crawl.SeedsFile) {
+					t.Fatalf("heritrix job %s was not built with beans of crawl %d: %q", crawl.HeritrixJob, crawl.ID, built)
+				}
+				temporary, err := filepath.Glob(filepath.Join(jobDir, CrawlerBeansName+".tmp-*"))
+				if err != nil || len(temporary) > 0 {
+					t.Fatalf("temporary beans were left: %v %v", temporary, err)
+				}
+			}
+		})
+	}
+}
+
+func TestJobRefusesUnfinishedJob(t *testing.T) {
+	server := heritrixtest.NewServer("admin", "secret")
+	defer server.Close()
+	server.AddJob(testJobName)
+
+	app := newTestApp(t)
+	job := newTestJob(t, server, 2)
+
+	// Logs and config of launch killed before cleanup
+	jobDir := filepath.Join(job.JobsDir, testJobName)
+	launchDir, err := filepath.Abs(filepath.Join(jobDir, "20240101000000"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.MkdirAll(filepath.Join(launchDir, "logs"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(launchDir, "logs", "crawl.log"), "left\n")
+	err = os.Symlink(launchDir, filepath.Join(jobDir, "latest"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(jobDir, CrawlerBeansName), "unfinished")
+
+	err = job.run(context.Background(), app)
+	var unfinished *UnfinishedJobError
+	if !errors.As(err, &unfinished) {
+		t.Fatalf("expected unfinished job error, got %v", err)
+	}
+	if len(server.Actions(testJobName)) != 0 {
+		t.Fatalf("crawl was started: %v", server.Actions(testJobName))
+	}
+	beans, err := os.ReadFile(filepath.Join(jobDir, CrawlerBeansName))
+	if err != nil || string(beans) != "unfinished" {
+		t.Fatalf("config of unfinished crawl was replaced: %q %v", beans, err)
+	}
+
+	force := true
+	app.ForceFlag = &force
+	err = job.resume(context.Background(), app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(server.Actions(testJobName), expectedActions(1)) {
+		t.Fatalf("got actions %v", server.Actions(testJobName))
+	}
+}
@@ -0,0 +1,219 @@
+package silence
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+const DefaultLockName = "silence.lock"
+
+// Information about the process holding the lock, it is written into the lock file.
+type LockInfo struct {
+	PID     int
+	Host    string
+	Started time.Time
+	Job     string
+}
+
+func (info *LockInfo) String() string {
+	return fmt.Sprintf("pid:%d host:%s started:%s job:%s",
+		info.PID, info.Host, info.Started.Format(time.RFC3339), info.Job)
+}
+
+// Error returned when the lock is held by another live process.
+type LockedError struct {
+	Path   string
+	Holder *LockInfo
+	// Process in the lock file is dead, but something, most likely its
+	// orphaned child, still holds the lock
+	HolderDead bool
+}
+
+func (err *LockedError) Error() string {
+	if err.Holder == nil {
+		return fmt.Sprintf("lock %s is held by another process", err.Path)
+	}
+	if err.HolderDead {
+		return fmt.Sprintf("lock %s of dead process (%s) is still held, probably by its child", err.Path, err.Holder)
+	}
+	return fmt.Sprintf("lock %s is held by another process (%s)", err.Path, err.Holder)
+}
+
+// Exclusive lock preventing two instances of silence running at once.
+// It is backed by flock, so the kernel releases it if the process dies,
+// the information inside the file is only for operators and stale lock detection.
+type Lock struct {
+	path string
+	file *os.File
+	info LockInfo
+
+	// Information left in the lock file by previous holder that is no longer alive.
+	Reclaimed *LockInfo
+}
+
+func DefaultLockPath() string {
+	return filepath.Join(os.TempDir(), DefaultLockName)
+}
+
+func AcquireLock(path string) (*Lock, error) {
+	host, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	info := LockInfo{
+		PID:     os.Getpid(),
+		Host:    host,
+		Started: time.Now(),
+	}
+
+	// Second attempt is used only when the file was replaced while we
+	// were waiting for flock.
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, err
+		}
+
+		err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err != nil {
+			file.Close()
+			if !errors.Is(err, syscall.EWOULDBLOCK) {
+				return nil, fmt.Errorf("flock on %s failed: %w", path, err)
+			}
+
+			// Lock held by any live process is never taken over, even
+			// when the process in the file is dead, stale lock is only
+			// reclaimed after flock succeeds
+			holder, _ := readLockInfo(path)
+			dead := holder != nil && holder.Host == host && !processAlive(holder.PID)
+			return nil, &LockedError{Path: path, Holder: holder, HolderDead: dead}
+		}
+
+		same, err := sameFile(file, path)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		if !same {
+			// Previous holder removed the file between open and flock.
+			file.Close()
+			continue
+		}
+
+		lock := &Lock{path: path, file: file, info: info}
+		previous, _ := decodeLockInfo(file)
+		if previous != nil && previous.PID != 0 {
+			lock.Reclaimed = previous
+		}
+
+		err = lock.write()
+		if err != nil {
+			lock.Release()
+			return nil, err
+		}
+		return lock, nil
+	}
+
+	return nil, fmt.Errorf("failed to acquire lock %s", path)
+}
+
+func (lock *Lock) Path() string {
+	return lock.path
+}
+
+// Records name of the job in the lock file.
+func (lock *Lock) SetJob(name string) error {
+	lock.info.Job = name
+	return lock.write()
+}
+
+// Removes the lock file and releases the lock. It is safe to call multiple times.
+func (lock *Lock) Release() error {
+	if lock == nil || lock.file == nil {
+		return nil
+	}
+
+	// Remove before unlocking so nobody can lock the file we are removing.
+	err := os.Remove(lock.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	err = syscall.Flock(int(lock.file.Fd()), syscall.LOCK_UN)
+	closeErr := lock.file.Close()
+	lock.file = nil
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+func (lock *Lock) write() error {
+	data, err := json.MarshalIndent(lock.info, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = lock.file.Truncate(0)
+	if err != nil {
+		return err
+	}
+	_, err = lock.file.WriteAt(append(data, '\n'), 0)
+	if err != nil {
+		return err
+	}
+	return lock.file.Sync()
+}
+
+func readLockInfo(path string) (*LockInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return decodeLockInfo(file)
+}
+
+func decodeLockInfo(r io.ReaderAt) (*LockInfo, error) {
+	data, err := io.ReadAll(io.NewSectionReader(r, 0, 1<<16))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	info := new(LockInfo)
+	err = json.Unmarshal(data, info)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func sameFile(file *os.File, path string) (bool, error) {
+	opened, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	current, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return os.SameFile(opened, current), nil
+}
+
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,185 @@
+package silence
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Returns PID of child process that already exited.
+func deadPID(t *testing.T) int {
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	err := cmd.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cmd.Process.Pid
+}
+
+// Writes info into lock file at path and, when held, keeps flock on it
+// until the end of the test, like a process that inherited the descriptor.
+func writeTestLock(t *testing.T, path string, info LockInfo, held bool) {
+	data, err := json.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !held {
+		return
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAcquireLock(t *testing.T) {
+	host, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dead := deadPID(t)
+
+	tests := []struct {
+		name string
+		// Prepares the lock file
+		setup func(t *testing.T, path string)
+		// Holder reported by LockedError, nil when the lock must be acquired
+		holder     func() *LockInfo
+		holderDead bool
+		reclaimed  bool
+	}{
+		{
+			name:  "free",
+			setup: func(t *testing.T, path string) {},
+		},
+		{
+			name: "live holder",
+			setup: func(t *testing.T, path string) {
+				writeTestLock(t, path, LockInfo{PID: os.Getpid(), Host: host, Job: "topics"}, true)
+			},
+			holder: func() *LockInfo { return &LockInfo{PID: os.Getpid(), Host: host, Job: "topics"} },
+		},
+		{
+			name: "dead holder released the lock",
+			setup: func(t *testing.T, path string) {
+				writeTestLock(t, path, LockInfo{PID: dead, Host: host}, false)
+			},
+			reclaimed: true,
+		},
+		{
+			name: "dead holder with lock kept by orphan",
+			setup: func(t *testing.T, path string) {
+				writeTestLock(t, path, LockInfo{PID: dead, Host: host}, true)
+			},
+			holder:     func() *LockInfo { return &LockInfo{PID: dead, Host: host} },
+			holderDead: true,
+		},
+		{
+			name: "holder on foreign host",
+			setup: func(t *testing.T, path string) {
+				writeTestLock(t, path, LockInfo{PID: 1, Host: "other." + host}, true)
+			},
+			holder: func() *LockInfo { return &LockInfo{PID: 1, Host: "other." + host} },
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), DefaultLockName)
+			test.setup(t, path)
+
+			lock, err := AcquireLock(path)
+			if test.holder != nil {
+				var locked *LockedError
+				if !errors.As(err, &locked) {
+					t.Fatalf("expected locked error, got %v", err)
+				}
+				expected := test.holder()
+				if locked.Holder == nil || locked.Holder.PID != expected.PID || locked.Holder.Host != expected.Host {
+					t.Fatalf("expected holder %s, got %v", expected, locked.Holder)
+				}
+				if locked.HolderDead != test.holderDead {
+					t.Fatalf("unexpected dead holder in %v", locked)
+				}
+				// Lock that is still held must stay in place
+				_, err = os.Stat(path)
+				if err != nil {
+					t.Fatalf("lock file was removed: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer lock.Release()
+
+			if (lock.Reclaimed != nil) != test.reclaimed {
+				t.Fatalf("unexpected reclaimed info %v", lock.Reclaimed)
+			}
+			info, err := readLockInfo(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.PID != os.Getpid() || info.Host != host {
+				t.Fatalf("lock file has %s", info)
+			}
+		})
+	}
+}
+
+func TestLockRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultLockName)
+	lock, err := AcquireLock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = lock.SetJob("topics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := readLockInfo(path)
+	if err != nil || info.Job != "topics" || time.Since(info.Started) > time.Minute {
+		t.Fatalf("unexpected lock info %v: %v", info, err)
+	}
+
+	_, err = AcquireLock(path)
+	var locked *LockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("second lock must be refused, got %v", err)
+	}
+
+	err = lock.Release()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = os.Stat(path)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("lock file was not removed: %v", err)
+	}
+	// Release is safe to call again
+	err = lock.Release()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err = AcquireLock(path)
+	if err != nil {
+		t.Fatalf("released lock cannot be acquired: %v", err)
+	}
+	lock.Release()
+}
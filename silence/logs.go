@@ -0,0 +1,239 @@
+package silence
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Directory inside harvest directory where archives of crawl logs are stored
+const LogArchiveDirectory = "logs/crawl"
+
+// Archives all files in logsDir into gzipped tar at archivePath, verifies
+// the archive and removes the originals. Returns names of archived files
+// relative to logsDir. Error is returned also when some logs remain in logsDir.
+func archiveLogs(logsDir string, archivePath string) ([]string, error) {
+	_, err := os.Stat(archivePath)
+	if err == nil {
+		return nil, fmt.Errorf("archive %s already exists", archivePath)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	files, err := listLogs(logsDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(archivePath), 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	// Archive is written under temporary name, so half written archive
+	// is never mistaken for complete one.
+	tmpPath := archivePath + ".tmp"
+	err = writeLogArchive(logsDir, files, tmpPath)
+	if err != nil {
+		os.Remove(tmpPath)
+		return nil, err
+	}
+
+	err = verifyLogArchive(tmpPath, files)
+	if err != nil {
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("verification of %s failed: %w", archivePath, err)
+	}
+
+	err = os.Rename(tmpPath, archivePath)
+	if err != nil {
+		os.Remove(tmpPath)
+		return nil, err
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		err = os.Remove(filepath.Join(logsDir, name))
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	remaining, err := listLogs(logsDir)
+	if err != nil {
+		return names, err
+	}
+	if len(remaining) > 0 {
+		return names, fmt.Errorf("%d files remain in %s after archiving", len(remaining), logsDir)
+	}
+
+	return names, nil
+}
+
+// Returns path of file in dir that does not exist yet, numeric suffix is
+// added to name when needed. Crawl that was interrupted and run again
+// has more archives of logs.
+func unusedPath(dir string, name string, extension string) (string, error) {
+	path := filepath.Join(dir, name+extension)
+	for i := 1; ; i++ {
+		_, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return path, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d%s", name, i, extension))
+	}
+}
+
+// Returns regular files in dir with their sizes, names are relative to dir.
+func listLogs(dir string) (map[string]int64, error) {
+	files := make(map[string]int64)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[name] = info.Size()
+		return nil
+	})
+	return files, err
+}
+
+func writeLogArchive(logsDir string, files map[string]int64, archivePath string) error {
+	archive, err := os.OpenFile(archivePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	gzipWriter := gzip.NewWriter(archive)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		err = addLogToArchive(tarWriter, filepath.Join(logsDir, name), name, files[name])
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tarWriter.Close()
+	if err != nil {
+		return err
+	}
+	err = gzipWriter.Close()
+	if err != nil {
+		return err
+	}
+	err = archive.Sync()
+	if err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+func addLogToArchive(tarWriter *tar.Writer, path string, name string, size int64) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() != size {
+		return fmt.Errorf("%s changed size during archiving, is the crawl still running?", path)
+	}
+
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = filepath.ToSlash(name)
+
+	err = tarWriter.WriteHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tarWriter, file)
+	return err
+}
+
+// Reads whole archive and checks it contains all files with expected sizes.
+func verifyLogArchive(archivePath string, files map[string]int64) error {
+	archive, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	gzipReader, err := gzip.NewReader(archive)
+	if err != nil {
+		return err
+	}
+	defer gzipReader.Close()
+
+	found := make(map[string]bool, len(files))
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		name := filepath.FromSlash(header.Name)
+		size, ok := files[name]
+		if !ok {
+			return fmt.Errorf("unexpected file %s in archive", header.Name)
+		}
+		written, err := io.Copy(io.Discard, tarReader)
+		if err != nil {
+			return err
+		}
+		if written != size {
+			return fmt.Errorf("%s has %d bytes in archive, expected %d", header.Name, written, size)
+		}
+		found[name] = true
+	}
+
+	for name := range files {
+		if !found[name] {
+			return fmt.Errorf("%s is missing in archive", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,278 @@
+package silence
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"silence/heritrix"
+	"slices"
+	"sync"
+	"time"
+)
+
+const MetricsPath = "/metrics"
+
+// Controller states exported as silence_heritrix_controller_state,
+// unknown states are added when they are seen.
+var metricsStates = []string{
+	heritrix.StateNascent,
+	heritrix.StatePreparing,
+	heritrix.StatePaused,
+	heritrix.StatePausing,
+	heritrix.StateRunning,
+	heritrix.StateStopping,
+	heritrix.StateFinished,
+	heritrix.StateEmpty,
+}
+
+type actionLatency struct {
+	count int64
+	sum   time.Duration
+}
+
+// Progress of one crawl, exported with label part, so crawls running in
+// parallel do not overwrite each other.
+type partProgress struct {
+	state      string
+	downloaded int64
+	queued     int64
+	bytes      int64
+	deadline   time.Time
+}
+
+// Metrics of the job exported in Prometheus text format.
+type metrics struct {
+	mu sync.Mutex
+
+	partIndex    int
+	totalParts   int
+	partsRunning int
+	parts        map[int]*partProgress
+	partIDs      []int
+	pollFailures int64
+	actions      map[string]*actionLatency
+	actionNames  []string
+	stateNames   []string
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		partIndex:  -1,
+		parts:      make(map[int]*partProgress),
+		actions:    make(map[string]*actionLatency),
+		stateNames: slices.Clone(metricsStates),
+	}
+}
+
+func (m *metrics) setParts(total int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.totalParts = total
+}
+
+// Marks crawl with id as started and forgets its progress from a previous
+// attempt. When crawls run in parallel, partIndex is the last started one.
+func (m *metrics) startPart(id int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.partsRunning++
+	m.partIndex = id
+	if _, ok := m.parts[id]; !ok {
+		m.partIDs = append(m.partIDs, id)
+		slices.Sort(m.partIDs)
+	}
+	m.parts[id] = new(partProgress)
+}
+
+// Returns progress of crawl with id, it is created for crawl that was not
+// started through startPart.
+func (m *metrics) part(id int) *partProgress {
+	progress, ok := m.parts[id]
+	if !ok {
+		progress = new(partProgress)
+		m.parts[id] = progress
+		m.partIDs = append(m.partIDs, id)
+		slices.Sort(m.partIDs)
+	}
+	return progress
+}
+
+func (m *metrics) finishPart() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.partsRunning--
+}
+
+func (m *metrics) observe(id int, status *heritrix.Job) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	progress := m.part(id)
+	if status.ControllerState != "" {
+		progress.state = status.ControllerState
+		if !slices.Contains(m.stateNames, progress.state) {
+			m.stateNames = append(m.stateNames, progress.state)
+		}
+	}
+	if status.URITotals != nil {
+		progress.downloaded = status.URITotals.DownloadedURICount
+		progress.queued = status.URITotals.QueuedURICount
+	}
+	if status.SizeTotals != nil {
+		progress.bytes = status.SizeTotals.Total
+	}
+}
+
+func (m *metrics) pollFailed() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pollFailures++
+}
+
+func (m *metrics) observeAction(action string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	latency, ok := m.actions[action]
+	if !ok {
+		latency = new(actionLatency)
+		m.actions[action] = latency
+		m.actionNames = append(m.actionNames, action)
+		slices.Sort(m.actionNames)
+	}
+	latency.count++
+	latency.sum += d
+}
+
+// Sets time when waiting for crawl with id ends, zero time clears it.
+func (m *metrics) setDeadline(id int, deadline time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.part(id).deadline = deadline
+}
+
+// Writes all metrics in Prometheus text exposition format.
+func (m *metrics) write(w io.Writer, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := &metricsPrinter{w: w}
+	p.metric("silence_part_index", "gauge", "ID of the crawl started last, -1 before the first one.")
+	p.sample("silence_part_index", "", float64(m.partIndex))
+	p.metric("silence_parts_total", "gauge", "Number of crawls of the job.")
+	p.sample("silence_parts_total", "", float64(m.totalParts))
+	p.metric("silence_parts_running", "gauge", "Number of crawls running at the moment.")
+	p.sample("silence_parts_running", "", float64(m.partsRunning))
+
+	p.metric("silence_heritrix_controller_state", "gauge", "Controller state of the crawl, 1 for the state Heritrix reported last.")
+	for _, id := range m.partIDs {
+		for _, state := range m.stateNames {
+			value := 0.0
+			if state == m.parts[id].state {
+				value = 1
+			}
+			p.sample("silence_heritrix_controller_state", fmt.Sprintf(`part="%d",state="%s"`, id, state), value)
+		}
+	}
+
+	p.metric("silence_uris_downloaded", "gauge", "URIs downloaded by the crawl.")
+	m.samplePerPart(p, "silence_uris_downloaded", func(progress *partProgress) float64 {
+		return float64(progress.downloaded)
+	})
+	p.metric("silence_uris_queued", "gauge", "URIs queued by the crawl.")
+	m.samplePerPart(p, "silence_uris_queued", func(progress *partProgress) float64 {
+		return float64(progress.queued)
+	})
+	p.metric("silence_bytes_written", "gauge", "Bytes downloaded and written by the crawl.")
+	m.samplePerPart(p, "silence_bytes_written", func(progress *partProgress) float64 {
+		return float64(progress.bytes)
+	})
+
+	p.metric("silence_poll_failures_total", "counter", "Failed checks of crawl status.")
+	p.sample("silence_poll_failures_total", "", float64(m.pollFailures))
+
+	p.metric("silence_action_duration_seconds", "summary", "Duration of actions sent to Heritrix, including waiting after them.")
+	for _, action := range m.actionNames {
+		latency := m.actions[action]
+		label := fmt.Sprintf(`action="%s"`, action)
+		p.sample("silence_action_duration_seconds_sum", label, latency.sum.Seconds())
+		p.sample("silence_action_duration_seconds_count", label, float64(latency.count))
+	}
+
+	p.metric("silence_crawl_remaining_seconds", "gauge", "Time left before MaxWaitSeconds of the crawl expires, 0 when not waiting.")
+	m.samplePerPart(p, "silence_crawl_remaining_seconds", func(progress *partProgress) float64 {
+		if progress.deadline.IsZero() {
+			return 0
+		}
+		return max(progress.deadline.Sub(now).Seconds(), 0)
+	})
+
+	return p.err
+}
+
+// Writes sample of every started crawl with label part.
+func (m *metrics) samplePerPart(p *metricsPrinter, name string, value func(*partProgress) float64) {
+	for _, id := range m.partIDs {
+		p.sample(name, fmt.Sprintf(`part="%d"`, id), value(m.parts[id]))
+	}
+}
+
+// Writes lines of text format, the first error is kept and stops writing.
+type metricsPrinter struct {
+	w   io.Writer
+	err error
+}
+
+func (p *metricsPrinter) metric(name, kind, help string) {
+	p.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func (p *metricsPrinter) sample(name, labels string, value float64) {
+	if labels != "" {
+		name = name + "{" + labels + "}"
+	}
+	p.printf("%s %g\n", name, value)
+}
+
+func (p *metricsPrinter) printf(format string, args ...any) {
+	if p.err != nil {
+		return
+	}
+	_, p.err = fmt.Fprintf(p.w, format, args...)
+}
+
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.write(w, time.Now())
+}
+
+// Serves metrics of the job on address from --metrics-listen. Returns
+// function that stops the server, it does nothing when the flag is not set.
+func (app *App) serveMetrics(job *Job) (func(), error) {
+	if app.MetricsListenFlag == nil || *app.MetricsListenFlag == "" {
+		return func() {}, nil
+	}
+
+	listener, err := net.Listen("tcp", *app.MetricsListenFlag)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(MetricsPath, job.metrics)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		err := server.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			app.Log.Error("metrics server failed", slog.String(ErrorKey, err.Error()))
+		}
+	}()
+	app.Log.Info("serving metrics", slog.String("address", listener.Addr().String()+MetricsPath))
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}, nil
+}
@@ -0,0 +1,54 @@
+package silence
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"silence/heritrix/heritrixtest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsAfterRun(t *testing.T) {
+	server := heritrixtest.NewServer("admin", "secret")
+	defer server.Close()
+	server.JobsDir = t.TempDir()
+	server.AddJob(testJobName)
+
+	app := newTestApp(t)
+	job := newTestJob(t, server, 5)
+
+	err := job.run(context.Background(), app)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metricsServer := httptest.NewServer(job.metrics)
+	defer metricsServer.Close()
+	response, err := http.Get(metricsServer.URL + MetricsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{
+		"silence_part_index 2",
+		"silence_parts_total 3",
+		`silence_heritrix_controller_state{part="0",state="FINISHED"} 1`,
+		`silence_heritrix_controller_state{part="2",state="FINISHED"} 1`,
+		`silence_heritrix_controller_state{part="2",state="RUNNING"} 0`,
+		"silence_poll_failures_total 0",
+		`silence_action_duration_seconds_count{action="build"} 3`,
+		`silence_action_duration_seconds_count{action="teardown"} 3`,
+		`silence_crawl_remaining_seconds{part="2"} 0`,
+	} {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Errorf("metrics do not contain %q:\n%s", line, body)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package silence
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"silence/heritrix"
+	"slices"
+)
+
+type PartJobsMethod string
+
+const (
+	// Heritrix creates the job with default profile in its jobs directory
+	PartJobsCreate PartJobsMethod = "create"
+	// Silence writes the job directory into Dir and adds it to Heritrix
+	PartJobsAdd PartJobsMethod = "add"
+)
+
+func (method PartJobsMethod) valid() bool {
+	return method == PartJobsCreate || method == PartJobsAdd
+}
+
+// Dedicated Heritrix job for every part, so job history, logs and
+// checkpoints of the parts are not mixed in one job.
+type PartJobsConfig struct {
+	Enabled bool
+	Method  PartJobsMethod
+	// Directory of jobs added by "add", Heritrix must see it at the same path
+	Dir string
+}
+
+func DefaultPartJobsConfig() *PartJobsConfig {
+	return &PartJobsConfig{Method: PartJobsCreate}
+}
+
+func (pc *PartJobsConfig) validate(v *validator) {
+	if !pc.Enabled {
+		return
+	}
+	v.check(pc.Method.valid(), "PartJobs.Method %q is not one of %q, %q", pc.Method, PartJobsCreate, PartJobsAdd)
+	v.check(pc.Method != PartJobsAdd || pc.Dir != "", "PartJobs.Dir must be set for method %q", PartJobsAdd)
+}
+
+// Name of the Heritrix job of the part, the crawl name with timestamp of
+// the run. Crawl name has only the day, so another run of the job on the
+// same day gets its own jobs as well, resumed run keeps the timestamp.
+func (crawl *Crawl) partJobName() string {
+	return crawl.config().CrawlName() + "-" + crawl.Timestamp
+}
+
+// Makes sure the Heritrix job of the part exists, job left by interrupted
+// run is used again. Job added from Dir is only prepared here, it is added
+// by addPartJob after crawler-beans are written into it.
+func (crawl *Crawl) preparePartJob(ctx context.Context, app *App) error {
+	client := crawl.client()
+	engine, err := client.Engine(ctx)
+	if err != nil {
+		return err
+	}
+	crawl.partJobExists = slices.ContainsFunc(engine.Jobs, func(job heritrix.EngineJob) bool {
+		return job.ShortName == crawl.HeritrixJob
+	})
+
+	config := crawl.Job.PartJobs
+	switch {
+	case crawl.partJobExists:
+		app.Log.Info(
+			fmt.Sprintf("heritrix job %s of interrupted run is used again", crawl.HeritrixJob),
+		)
+	case config.Method == PartJobsCreate:
+		err = client.CreateJob(ctx, crawl.HeritrixJob)
+		if err != nil {
+			return fmt.Errorf("failed to create heritrix job %s: %w", crawl.HeritrixJob, err)
+		}
+		crawl.partJobExists = true
+		app.Log.Info(fmt.Sprintf("heritrix job %s created", crawl.HeritrixJob))
+	case config.Method == PartJobsAdd:
+		dir, err := filepath.Abs(filepath.Join(config.Dir, crawl.HeritrixJob))
+		if err != nil {
+			return err
+		}
+		err = os.MkdirAll(dir, 0755)
+		if err != nil {
+			return err
+		}
+		crawl.partJobDir = dir
+		return nil
+	}
+
+	status, err := client.Job(ctx, crawl.HeritrixJob)
+	if err != nil {
+		return err
+	}
+	if status.PrimaryConfig == "" {
+		return fmt.Errorf("heritrix job %s has no primary config", crawl.HeritrixJob)
+	}
+	crawl.partJobDir = filepath.Dir(status.PrimaryConfig)
+	return nil
+}
+
+// Adds job directory written by silence to Heritrix.
+func (crawl *Crawl) addPartJob(ctx context.Context, app *App) error {
+	if crawl.partJobExists || crawl.Job.PartJobs.Method != PartJobsAdd {
+		return nil
+	}
+
+	err := crawl.client().AddJobDir(ctx, crawl.partJobDir)
+	if err != nil {
+		return fmt.Errorf("failed to add heritrix job %s: %w", crawl.HeritrixJob, err)
+	}
+	crawl.partJobExists = true
+	app.Log.Info(
+		fmt.Sprintf("heritrix job %s added", crawl.HeritrixJob),
+		slog.String("dir", crawl.partJobDir),
+	)
+	return nil
+}
@@ -0,0 +1,310 @@
+package silence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"path/filepath"
+	"silence/heritrix"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Heritrix instance crawls are run on.
+type CrawlerEndpoint struct {
+	Address  string
+	Username string
+	Password string
+	// Heritrix job of the first slot, slot k > 0 uses JobName-k.
+	// Default is Name of the job.
+	JobName string
+	// Number of crawls running on the instance at once, 0 means 1.
+	// Each of them needs its own Heritrix job.
+	Concurrency int
+	// Jobs directory of the instance as seen from here, crawler-beans are
+	// written into its subdirectories. When empty, the primary config
+	// reported by Heritrix is used.
+	JobsDir string
+
+	client *heritrix.Client
+	down   bool
+
+	// Crawls running on the instance and whether a crawl restarts it,
+	// changed by the scheduler and crawls
+	mu         sync.Mutex
+	active     int
+	restarting bool
+}
+
+func (endpoint *CrawlerEndpoint) concurrency() int {
+	return max(endpoint.Concurrency, 1)
+}
+
+// Address and Heritrix jobs of the endpoint, for logging.
+// Lists jobs of all slots of the endpoint, e.g. "host:8443/topics,topics-1".
+func (endpoint *CrawlerEndpoint) String() string {
+	names := make([]string, endpoint.concurrency())
+	for k := range names {
+		names[k] = endpoint.slotJobName(k)
+	}
+	return fmt.Sprintf("%s/%s", endpoint.Address, strings.Join(names, ","))
+}
+
+// Name of the Heritrix job used by slot k of the endpoint.
+func (endpoint *CrawlerEndpoint) slotJobName(k int) string {
+	if k == 0 {
+		return endpoint.JobName
+	}
+	return fmt.Sprintf("%s-%d", endpoint.JobName, k)
+}
+
+func (endpoint *CrawlerEndpoint) validate(v *validator, i int) {
+	field := fmt.Sprintf("Crawlers[%d]", i)
+	validateAddress(v, field+".Address", endpoint.Address)
+	v.check(endpoint.Username != "", "%s.Username must be set", field)
+	v.check(endpoint.Password != "", "%s.Password must be set", field)
+	v.check(endpoint.JobName == "" || safeNamePattern.MatchString(endpoint.JobName),
+		"%s.JobName %q is not safe for Heritrix job directory", field, endpoint.JobName)
+	v.check(endpoint.Concurrency >= 0, "%s.Concurrency must not be negative", field)
+}
+
+// Counts new crawl on the instance, fails while the instance is restarted.
+func (endpoint *CrawlerEndpoint) acquire() bool {
+	endpoint.mu.Lock()
+	defer endpoint.mu.Unlock()
+	if endpoint.restarting {
+		return false
+	}
+	endpoint.active++
+	return true
+}
+
+func (endpoint *CrawlerEndpoint) release() {
+	endpoint.mu.Lock()
+	defer endpoint.mu.Unlock()
+	endpoint.active--
+}
+
+// Takes the instance for restart when the calling crawl is the only one
+// running there, no crawl is started on it until endRestart. Returns
+// number of other running crawls when they prevent the restart.
+func (endpoint *CrawlerEndpoint) beginRestart() (int, bool) {
+	endpoint.mu.Lock()
+	defer endpoint.mu.Unlock()
+	if endpoint.active > 1 || endpoint.restarting {
+		return endpoint.active - 1, false
+	}
+	endpoint.restarting = true
+	return 0, true
+}
+
+func (endpoint *CrawlerEndpoint) endRestart() {
+	endpoint.mu.Lock()
+	defer endpoint.mu.Unlock()
+	endpoint.restarting = false
+}
+
+// One Heritrix job of an endpoint, it runs one crawl at a time.
+type crawlerSlot struct {
+	endpoint *CrawlerEndpoint
+	jobName  string
+}
+
+func (slot *crawlerSlot) String() string {
+	return fmt.Sprintf("%s/%s", slot.endpoint.Address, slot.jobName)
+}
+
+// Creates clients of all endpoints. Without Crawlers the job has single
+// endpoint made from CrawlerAddress and credentials.
+func (job *Job) initPool(app *App) error {
+	endpoints := job.Crawlers
+	if len(endpoints) == 0 {
+		endpoints = []*CrawlerEndpoint{{
+			Address:  job.CrawlerAddress,
+			Username: job.CrawlerUsername,
+			Password: job.CrawlerPassword,
+			JobsDir:  job.JobsDir,
+		}}
+		// Heritrix started by silence uses jobs directory in its home
+		if endpoints[0].JobsDir == "" && job.Heritrix.managed() {
+			endpoints[0].JobsDir = filepath.Join(job.Heritrix.Home, "jobs")
+		}
+	}
+
+	for _, endpoint := range endpoints {
+		client, err := heritrix.NewClient(endpoint.Address, endpoint.Username, endpoint.Password)
+		if err != nil {
+			return fmt.Errorf("crawler %s: %w", endpoint.Address, err)
+		}
+		client.SetRetryPolicy(job.Retry.policy(app))
+		endpoint.client = client
+		if endpoint.JobName == "" {
+			endpoint.JobName = job.Name
+		}
+	}
+
+	job.pool = endpoints
+	job.client = endpoints[0].client
+	return nil
+}
+
+// Returns slots of all endpoints that are not down, the first slots of
+// all endpoints go first, so crawls are spread over the instances.
+func (job *Job) slots() []*crawlerSlot {
+	var slots []*crawlerSlot
+	for k := 0; ; k++ {
+		added := false
+		for _, endpoint := range job.pool {
+			if endpoint.down || k >= endpoint.concurrency() {
+				continue
+			}
+			slots = append(slots, &crawlerSlot{endpoint: endpoint, jobName: endpoint.slotJobName(k)})
+			added = true
+		}
+		if !added {
+			return slots
+		}
+	}
+}
+
+// Reports whether the error means Heritrix instance is not reachable.
+// Crawl that failed this way can be run elsewhere.
+func instanceDown(err error) bool {
+	var statusErr *heritrix.StatusError
+	if errors.As(err, &statusErr) {
+		return false
+	}
+	var netErr net.Error
+	var urlErr *url.Error
+	return errors.As(err, &netErr) || errors.As(err, &urlErr)
+}
+
+type crawlResult struct {
+	crawl *Crawl
+	slot  *crawlerSlot
+	err   error
+}
+
+// Logger of the crawl, every line says which part and Heritrix job it is.
+func (app *App) forCrawl(crawl *Crawl, slot *crawlerSlot) *App {
+	crawlApp := *app
+	heritrixJob := slot.String()
+	if crawl.HeritrixJob != "" {
+		heritrixJob = fmt.Sprintf("%s/%s", slot.endpoint.Address, crawl.HeritrixJob)
+	}
+	crawlApp.Log = app.Log.With(slog.Int("part", crawl.ID), slog.String("heritrix", heritrixJob))
+	return &crawlApp
+}
+
+// Runs crawls that are not finished on idle slots. Only this goroutine
+// changes status of crawls, workers only run them. When a crawl fails,
+// no new crawl is started and running ones are awaited. Crawl that failed
+// because its instance went down is queued again and the instance is not
+// used any more.
+func (job *Job) schedule(ctx context.Context, app *App) error {
+	var queue []*Crawl
+	for _, crawl := range job.crawls {
+		if crawl.Status != CrawlFinished {
+			queue = append(queue, crawl)
+		}
+	}
+
+	idle := job.slots()
+	results := make(chan crawlResult)
+	running := 0
+	var failure error
+	var interrupted error
+
+	for {
+		for len(queue) > 0 && failure == nil && ctx.Err() == nil {
+			i := -1
+			for j, slot := range idle {
+				if slot.endpoint.acquire() {
+					i = j
+					break
+				}
+			}
+			if i < 0 {
+				break
+			}
+			crawl, slot := queue[0], idle[i]
+			queue, idle = queue[1:], slices.Delete(idle, i, i+1)
+
+			crawl.slot = slot
+			if job.PartJobs.Enabled && crawl.HeritrixJob == "" {
+				crawl.HeritrixJob = crawl.partJobName()
+			}
+			crawlApp := app.forCrawl(crawl, slot)
+			crawlApp.Log.Info(fmt.Sprintf("starting crawl %d", crawl.ID))
+			job.metrics.startPart(crawl.ID)
+			job.setStatus(app, crawl, CrawlRunning)
+			running++
+			go func() {
+				results <- crawlResult{crawl, slot, crawl.Run(ctx, crawlApp)}
+			}()
+		}
+		if running == 0 {
+			break
+		}
+
+		result := <-results
+		running--
+		crawl, slot, err := result.crawl, result.slot, result.err
+		slot.endpoint.release()
+		crawlApp := app.forCrawl(crawl, slot)
+		job.metrics.finishPart()
+		if err != nil {
+			crawl.report.Error = err.Error()
+		}
+
+		switch {
+		case err == nil:
+			job.setStatus(app, crawl, CrawlFinished)
+			if !slot.endpoint.down {
+				idle = append(idle, slot)
+			}
+		case ctx.Err() != nil:
+			// Interrupted crawl will be run again by resume
+			job.setStatus(app, crawl, CrawlQueued)
+			crawlApp.Log.Warn(
+				fmt.Sprintf("crawl %d was interrupted", crawl.ID),
+				slog.String(ErrorKey, err.Error()),
+				slog.Int("id", crawl.ID),
+			)
+			interrupted = err
+		case instanceDown(err) && len(job.pool) > 1:
+			job.setStatus(app, crawl, CrawlQueued)
+			queue = append([]*Crawl{crawl}, queue...)
+			slot.endpoint.down = true
+			idle = slices.DeleteFunc(idle, func(slot *crawlerSlot) bool {
+				return slot.endpoint.down
+			})
+			crawlApp.Log.Error(
+				fmt.Sprintf("heritrix %s is down, crawl %d is queued again", slot.endpoint.Address, crawl.ID),
+				slog.String(ErrorKey, err.Error()),
+			)
+			if len(job.slots()) == 0 && failure == nil {
+				failure = errors.New("all heritrix instances are down")
+			}
+		default:
+			job.setStatus(app, crawl, CrawlFailed)
+			crawlApp.Log.Error(
+				fmt.Sprintf("error when processing crawl %d", crawl.ID),
+				slog.String(ErrorKey, err.Error()),
+				slog.Int("id", crawl.ID),
+			)
+			if failure == nil {
+				failure = err
+			}
+		}
+	}
+
+	if interrupted != nil {
+		return interrupted
+	}
+	return failure
+}
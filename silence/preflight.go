@@ -0,0 +1,88 @@
+package silence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"strings"
+)
+
+// Error returned when Heritrix job still holds crawl that was not finished
+// and cleaned, starting over it would mix or lose its data.
+type UnfinishedJobError struct {
+	Job     string
+	Reasons []string
+}
+
+func (err *UnfinishedJobError) Error() string {
+	return fmt.Sprintf("heritrix job %s has unfinished crawl (%s), check it or use --force to start anyway",
+		err.Job, strings.Join(err.Reasons, "; "))
+}
+
+func (app *App) force() bool {
+	return app.ForceFlag != nil && *app.ForceFlag
+}
+
+// Checks that the Heritrix job is not running and that the previous crawl
+// in it was cleaned, which means its logs were archived and no WARC is open
+// in store paths of the new crawl. It runs before anything is written into
+// the job with crawler-beans at beansPath. With --force problems are only logged.
+func (crawl *Crawl) preflight(ctx context.Context, app *App, beansPath string) error {
+	var reasons []string
+	// Directory of new job added by silence is not known to Heritrix yet
+	if crawl.HeritrixJob == "" || crawl.partJobExists {
+		status, err := crawl.client().Job(ctx, crawl.jobName())
+		if err != nil {
+			return err
+		}
+		crawl.observe(status)
+
+		if status.IsRunning {
+			reasons = append(reasons, fmt.Sprintf("job is running in state %s", status.ControllerState))
+		} else if status.ControllerState != "" {
+			reasons = append(reasons, fmt.Sprintf("job was not torn down, it is in state %s", status.ControllerState))
+		}
+	}
+
+	jobDir := filepath.Dir(beansPath)
+	// Heritrix points latest to directory of the last launch
+	logsDir := filepath.Join(jobDir, "latest", "logs")
+	logs, err := listLogs(logsDir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if len(logs) > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d logs of previous launch were not archived from %s", len(logs), logsDir))
+	}
+
+	// Store paths are usually dedicated to the crawl, see crawler-beans.template
+	rendered, err := crawl.renderCrawlBeans()
+	if err != nil {
+		return err
+	}
+	for _, dir := range resolveStorePaths(app, parseWarcStorePaths(rendered), jobDir) {
+		matches, err := filepath.Glob(filepath.Join(dir, openWarcPattern))
+		if err != nil {
+			return err
+		}
+		if len(matches) > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d open warcs found in %s", len(matches), dir))
+		}
+	}
+
+	if len(reasons) == 0 {
+		return nil
+	}
+	unfinished := &UnfinishedJobError{Job: crawl.jobName(), Reasons: reasons}
+	if app.force() {
+		app.Log.Warn(
+			"starting over unfinished crawl, because of --force",
+			slog.String(ErrorKey, unfinished.Error()),
+		)
+		return nil
+	}
+	return unfinished
+}
@@ -0,0 +1,112 @@
+package silence
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const reportTimestampFormat = "20060102150405"
+
+// Machine readable summary of a run, written to the working directory.
+type JobReport struct {
+	Job      string
+	Started  time.Time
+	Finished time.Time
+	Status   string
+	Error    string `json:",omitempty"`
+	Crawls   []*CrawlReport
+}
+
+// Status of JobReport
+const (
+	ReportFinished    = "finished"
+	ReportFailed      = "failed"
+	ReportInterrupted = "interrupted"
+)
+
+type CrawlReport struct {
+	ID        int
+	SeedsFile string
+	SeedCount int
+	Status    CrawlStatus
+	Started   *time.Time `json:",omitempty"`
+	Finished  *time.Time `json:",omitempty"`
+	// Dedicated Heritrix job of the part and its directory
+	HeritrixJob string `json:",omitempty"`
+	JobDir      string `json:",omitempty"`
+	// Copy of crawler-beans the part was crawled with
+	BeansCopy string `json:",omitempty"`
+
+	// Last state reported by Heritrix
+	ControllerState   string
+	ExitStatus        string
+	StatusDescription string
+	// Crawl was terminated because it run longer than MaxWaitSeconds
+	TimedOut bool
+	// Crawl was terminated early because it made no progress, see StallConfig
+	Stalled bool
+
+	Cleanup CleanupReport
+	Error   string `json:",omitempty"`
+}
+
+type CleanupReport struct {
+	Done           bool
+	WarcsRecovered int
+	WarcsUntouched int
+	LogArchive     string `json:",omitempty"`
+	Error          string `json:",omitempty"`
+}
+
+func reportFileName(started time.Time) string {
+	return fmt.Sprintf("report-%s.json", started.Format(reportTimestampFormat))
+}
+
+// Name of crawler-beans copy of the part kept next to the report.
+func beansCopyName(started time.Time, id int) string {
+	return fmt.Sprintf("crawler-beans-%s-%03d.cxml", started.Format(reportTimestampFormat), id)
+}
+
+// Writes report of the run started at started, err is the result of the run.
+func (job *Job) writeReport(app *App, started time.Time, interrupted bool, err error) {
+	report := JobReport{
+		Job:      job.Name,
+		Started:  started,
+		Finished: time.Now(),
+		Status:   ReportFinished,
+	}
+	if err != nil {
+		report.Status = ReportFailed
+		if interrupted {
+			report.Status = ReportInterrupted
+		}
+		report.Error = err.Error()
+	}
+
+	for _, crawl := range job.crawls {
+		crawlReport := crawl.report
+		crawlReport.ID = crawl.ID
+		crawlReport.SeedsFile = crawl.SeedsFile
+		crawlReport.Status = crawl.Status
+		crawlReport.HeritrixJob = crawl.HeritrixJob
+		crawlReport.JobDir = crawl.partJobDir
+		report.Crawls = append(report.Crawls, &crawlReport)
+	}
+
+	name := reportFileName(started)
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err == nil {
+		err = writeFileAtomic(name, data, 0644)
+	}
+	if err != nil {
+		app.Log.Error(
+			fmt.Sprintf("failed to write report %s", name),
+			slog.String(ErrorKey, err.Error()),
+		)
+		return
+	}
+
+	app.Log.Info("report written", slog.String("file", name))
+}
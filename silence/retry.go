@@ -0,0 +1,73 @@
+package silence
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"silence/heritrix"
+	"time"
+)
+
+// Retrying of requests to Heritrix, see heritrix.RetryPolicy.
+type RetryConfig struct {
+	// Number of attempts including the first one, 1 disables retrying
+	Attempts           int
+	InitialDelayMillis int
+	MaxDelaySeconds    int
+	Multiplier         float64
+	// Random change of each delay as fraction of it, 0 to 1
+	Jitter float64
+	// HTTP status codes that are retried
+	StatusCodes []int
+	// Retry when Heritrix is not reachable or connection breaks
+	NetworkErrors bool
+}
+
+func DefaultRetryConfig() *RetryConfig {
+	return &RetryConfig{
+		Attempts:           3,
+		InitialDelayMillis: 500,
+		MaxDelaySeconds:    30,
+		Multiplier:         2,
+		Jitter:             0.2,
+		StatusCodes: []int{
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		NetworkErrors: true,
+	}
+}
+
+func (rc *RetryConfig) validate(v *validator) {
+	v.check(rc.Attempts > 0, "Retry.Attempts must be bigger than 0")
+	v.check(rc.InitialDelayMillis >= 0, "Retry.InitialDelayMillis must not be negative")
+	v.check(rc.MaxDelaySeconds >= 0, "Retry.MaxDelaySeconds must not be negative")
+	v.check(rc.Multiplier >= 1, "Retry.Multiplier must be at least 1")
+	v.check(rc.Jitter >= 0 && rc.Jitter <= 1, "Retry.Jitter must be between 0 and 1")
+	for _, code := range rc.StatusCodes {
+		v.check(code >= 100 && code <= 599, "Retry.StatusCodes contains invalid status code %d", code)
+	}
+}
+
+// Returns policy for the client, every retry is logged as warning.
+func (rc *RetryConfig) policy(app *App) heritrix.RetryPolicy {
+	return heritrix.RetryPolicy{
+		Attempts:      rc.Attempts,
+		InitialDelay:  time.Duration(rc.InitialDelayMillis) * time.Millisecond,
+		MaxDelay:      time.Duration(rc.MaxDelaySeconds) * time.Second,
+		Multiplier:    rc.Multiplier,
+		Jitter:        rc.Jitter,
+		StatusCodes:   rc.StatusCodes,
+		NetworkErrors: rc.NetworkErrors,
+		OnRetry: func(method, path string, attempt int, err error, delay time.Duration) {
+			app.Log.Warn(
+				fmt.Sprintf("request %s %s failed, retrying", method, path),
+				slog.Int("attempt", attempt),
+				slog.Duration("delay", delay),
+				slog.String(ErrorKey, err.Error()),
+			)
+		},
+	}
+}
@@ -1,6 +1,7 @@
 package silence
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
@@ -18,6 +19,83 @@ const (
 )
 
 func Run(app *App) {
+	job := initJob(app)
+	if app.DryRunFlag != nil && *app.DryRunFlag {
+		err := job.dryRun(app, app.cmd.OutOrStdout())
+		app.finish(false, err)
+		return
+	}
+	stopMetrics := app.startMetrics(job)
+	ctx, stop := app.handleSignals(job)
+
+	err := job.run(ctx, app)
+	interrupted := ctx.Err() != nil
+	stop()
+	stopMetrics()
+	app.finish(interrupted, err)
+}
+
+// Continue crawls from state saved by interrupted run.
+func Resume(app *App) {
+	job := initJob(app)
+	stopMetrics := app.startMetrics(job)
+	ctx, stop := app.handleSignals(job)
+
+	err := job.resume(ctx, app)
+	interrupted := ctx.Err() != nil
+	stop()
+	stopMetrics()
+	app.finish(interrupted, err)
+}
+
+// Starts metrics server when requested. Exits on error, operator asked
+// for the metrics and would miss them during the whole job.
+func (app *App) startMetrics(job *Job) func() {
+	stop, err := app.serveMetrics(job)
+	if err != nil {
+		app.Log.Error(
+			"failed to start metrics server, exiting with error status",
+			slog.Int(StatusKey, ErrorStatus),
+			slog.String(ErrorKey, err.Error()),
+		)
+		app.exit(ErrorStatus)
+	}
+	return stop
+}
+
+// Exits with status according to the error returned by the job.
+func (app *App) finish(interrupted bool, err error) {
+	var teardownErr *TeardownTimeoutError
+	if errors.As(err, &teardownErr) {
+		app.Log.Error(
+			"heritrix job is stuck, check heritrix before the next run",
+			slog.Int(StatusKey, TeardownTimeoutStatus),
+			slog.String(ErrorKey, err.Error()),
+		)
+		app.exit(TeardownTimeoutStatus)
+	}
+	if err != nil && interrupted {
+		app.Log.Error(
+			"interrupted, remaining crawls can be run by resume command",
+			slog.Int(StatusKey, InterruptedStatus),
+			slog.String(ErrorKey, err.Error()),
+		)
+		app.exit(InterruptedStatus)
+	}
+	if err != nil {
+		app.Log.Error(
+			"fatal error, exiting with error status",
+			slog.Int(StatusKey, ErrorStatus),
+			slog.String(ErrorKey, err.Error()),
+		)
+		app.exit(ErrorStatus)
+	}
+
+	app.releaseLock()
+}
+
+// Checks job config without contacting Heritrix.
+func Validate(app *App) {
 	err := app.initApp()
 	if err != nil {
 		app.Log.Error(
@@ -28,36 +106,82 @@ func Run(app *App) {
 		os.Exit(ErrorStatus)
 	}
 
-	// TODO: Add locking mechanism to prevent two instances running at once.
-
-	app.Log.Debug("app is inicialized")
-
-	job, err := NewJob(app, DefaultJobConfigPath)
+	configPath, err := app.jobConfigPath()
 	if err != nil {
 		app.Log.Error(
-			"failed to create job, exiting with error status",
+			"failed to find job config, exiting with error status",
 			slog.Int(StatusKey, ErrorStatus),
 			slog.String(ErrorKey, err.Error()),
 		)
 		os.Exit(ErrorStatus)
 	}
 
-	if job.Name == "" {
+	// NewJob validates the config and logs all problems
+	_, err = NewJob(app, configPath)
+	if err != nil {
 		app.Log.Error(
-			"job name must be set",
+			"job config is invalid, exiting with error status",
+			slog.String("config", configPath),
 			slog.Int(StatusKey, ErrorStatus),
 		)
 		os.Exit(ErrorStatus)
 	}
-	app.Log.Info(fmt.Sprintf("job %s was inicialized", job.Name))
 
-	err = job.run(app)
+	app.Log.Info("job config is valid", slog.String("config", configPath))
+}
+
+// Initializes app, acquires lock and loads the job. Exits on any error.
+func initJob(app *App) *Job {
+	err := app.initApp()
 	if err != nil {
 		app.Log.Error(
-			"fatal error, exiting with error status",
+			"failed to inicialize, exiting with error status",
 			slog.Int(StatusKey, ErrorStatus),
 			slog.String(ErrorKey, err.Error()),
 		)
 		os.Exit(ErrorStatus)
 	}
+
+	err = app.acquireLock()
+	if err != nil {
+		app.Log.Error(
+			"failed to lock, exiting with error status",
+			slog.Int(StatusKey, ErrorStatus),
+		)
+		app.exit(ErrorStatus)
+	}
+
+	app.Log.Debug("app is inicialized")
+
+	configPath, err := app.jobConfigPath()
+	if err != nil {
+		app.Log.Error(
+			"failed to find job config, exiting with error status",
+			slog.Int(StatusKey, ErrorStatus),
+			slog.String(ErrorKey, err.Error()),
+		)
+		app.exit(ErrorStatus)
+	}
+
+	job, err := NewJob(app, configPath)
+	if err != nil {
+		app.Log.Error(
+			"failed to create job, exiting with error status",
+			slog.Int(StatusKey, ErrorStatus),
+			slog.String(ErrorKey, err.Error()),
+		)
+		app.exit(ErrorStatus)
+	}
+
+	app.Log.Info(fmt.Sprintf("job %s was inicialized", job.Name))
+
+	err = app.lock.SetJob(job.Name)
+	if err != nil {
+		app.Log.Warn(
+			"failed to write job name into lock",
+			slog.String(ErrorKey, err.Error()),
+		)
+	}
+
+	return job
 }
@@ -0,0 +1,152 @@
+package silence
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// Scheme added to seeds written without one, e.g. example.com/page
+const DefaultSeedScheme = "http"
+
+// Seed starts with scheme like mailto:, colon followed by digit is port
+// of seed without scheme, e.g. example.com:8080
+var schemePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*:[^0-9]`)
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Seed that cannot be crawled, with line number in the seeds file.
+type rejectedSeed struct {
+	Line   int
+	Seed   string
+	Reason string
+}
+
+// Counts of seeds after preprocessing.
+type seedCounts struct {
+	Lines      int
+	Skipped    int
+	Rejected   int
+	Duplicates int
+	Accepted   int
+}
+
+// Trims and canonicalizes seeds, skips empty lines and # comments and
+// removes duplicates. Seeds that are not valid URLs are rejected.
+func preprocessSeeds(lines []string) ([]string, []rejectedSeed, seedCounts) {
+	counts := seedCounts{Lines: len(lines)}
+	seen := make(map[string]bool, len(lines))
+	var seeds []string
+	var rejects []rejectedSeed
+
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			counts.Skipped++
+			continue
+		}
+
+		seed, err := canonicalizeSeed(line)
+		if err != nil {
+			counts.Rejected++
+			rejects = append(rejects, rejectedSeed{Line: i + 1, Seed: line, Reason: err.Error()})
+			continue
+		}
+
+		if seen[seed] {
+			counts.Duplicates++
+			continue
+		}
+		seen[seed] = true
+		seeds = append(seeds, seed)
+	}
+
+	counts.Accepted = len(seeds)
+	return seeds, rejects, counts
+}
+
+// Returns canonical form of the seed: scheme and host are lowercase, host
+// is in IDNA ASCII form, default port and fragment are removed and empty
+// path is replaced by /.
+func canonicalizeSeed(seed string) (string, error) {
+	if strings.ContainsAny(seed, " \t") {
+		return "", errors.New("contains whitespace")
+	}
+	if !strings.Contains(seed, "://") && !schemePattern.MatchString(seed) {
+		seed = DefaultSeedScheme + "://" + seed
+	}
+
+	parsed, err := url.Parse(seed)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse: %w", err)
+	}
+
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	defaultPort, ok := defaultPorts[parsed.Scheme]
+	if !ok {
+		return "", fmt.Errorf("unsupported scheme %s", parsed.Scheme)
+	}
+
+	host := strings.TrimSuffix(parsed.Hostname(), ".")
+	if host == "" {
+		return "", errors.New("missing host")
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		host = ip.String()
+	} else {
+		host, err = idna.Lookup.ToASCII(strings.ToLower(host))
+		if err != nil {
+			return "", fmt.Errorf("invalid host: %w", err)
+		}
+	}
+
+	port := parsed.Port()
+	switch {
+	case port != "" && port != defaultPort:
+		parsed.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		// IPv6 address
+		parsed.Host = "[" + host + "]"
+	default:
+		parsed.Host = host
+	}
+
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+	if parsed.Path == "" {
+		parsed.Path = "/"
+	}
+	return parsed.String(), nil
+}
+
+// Writes rejected seeds with reasons, one per line.
+func writeRejectedSeeds(path string, rejects []rejectedSeed) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	for _, reject := range rejects {
+		_, err = fmt.Fprintf(writer, "%d\t%s\t%s\n", reject.Line, reject.Seed, reject.Reason)
+		if err != nil {
+			return err
+		}
+	}
+	err = writer.Flush()
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,57 @@
+package silence
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCanonicalizeSeed(t *testing.T) {
+	cases := map[string]string{
+		"example.com":                     "http://example.com/",
+		"HTTPS://WWW.Example.COM:443/A?b": "https://www.example.com/A?b",
+		"http://example.com:8080/x#top":   "http://example.com:8080/x",
+		"http://bücher.de/":               "http://xn--bcher-kva.de/",
+		"http://[::1]:80/":                "http://[::1]/",
+	}
+	for seed, expected := range cases {
+		canonical, err := canonicalizeSeed(seed)
+		if err != nil {
+			t.Errorf("%s was rejected: %v", seed, err)
+			continue
+		}
+		if canonical != expected {
+			t.Errorf("%s was canonicalized to %s, expected %s", seed, canonical, expected)
+		}
+	}
+
+	for _, seed := range []string{"ftp://example.com/", "http://", "http://exa mple.com/", "http://example.com:port/"} {
+		_, err := canonicalizeSeed(seed)
+		if err == nil {
+			t.Errorf("%s was accepted", seed)
+		}
+	}
+}
+
+func TestPreprocessSeeds(t *testing.T) {
+	lines := []string{
+		"# comment",
+		"  https://example.com/a  ",
+		"",
+		"example.com/a",
+		"https://EXAMPLE.com/a",
+		"mailto:someone@example.com",
+		"http://example.org",
+	}
+
+	seeds, rejects, counts := preprocessSeeds(lines)
+	expected := []string{"https://example.com/a", "http://example.com/a", "http://example.org/"}
+	if !slices.Equal(seeds, expected) {
+		t.Fatalf("got seeds %v", seeds)
+	}
+	if len(rejects) != 1 || rejects[0].Line != 6 {
+		t.Fatalf("got rejects %+v", rejects)
+	}
+	if counts != (seedCounts{Lines: 7, Skipped: 2, Rejected: 1, Duplicates: 1, Accepted: 3}) {
+		t.Fatalf("got counts %+v", counts)
+	}
+}
@@ -0,0 +1,63 @@
+package silence
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Exit status used when the job was stopped by signal
+const InterruptedStatus = 130
+
+// Returns context canceled by the first SIGINT or SIGTERM, so the current
+// crawl can be stopped safely. Second signal exits immediately. Returned
+// function stops the handling.
+func (app *App) handleSignals(job *Job) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-signals:
+			app.Log.Warn(
+				"received signal, stopping current crawl safely, send it again to exit immediately",
+				slog.String("signal", sig.String()),
+			)
+			cancel()
+		case <-ctx.Done():
+			return
+		}
+
+		sig := <-signals
+		app.Log.Error(
+			"received second signal, exiting immediately, heritrix job may be still running and must be terminated and torn down by operator",
+			slog.String("signal", sig.String()),
+			slog.Any("heritrix_jobs", job.pool),
+			slog.Int(StatusKey, InterruptedStatus),
+		)
+		app.exit(InterruptedStatus)
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
+
+// Sleeps for d or until ctx is done, in that case ctx.Err() is returned.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
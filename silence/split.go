@@ -0,0 +1,182 @@
+package silence
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// How seeds are divided into crawls.
+type SplitStrategy string
+
+const (
+	// Consecutive chunks of MaxLines seeds, the original order is kept.
+	SplitLines SplitStrategy = "lines"
+	// Seeds of one registrable domain are kept in one crawl, unless the
+	// domain alone has more than MaxLines seeds.
+	SplitHost SplitStrategy = "host"
+)
+
+func (s SplitStrategy) valid() bool {
+	return s == SplitLines || s == SplitHost
+}
+
+// Reads all lines of the seeds file.
+func readSeeds(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var seeds []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		seeds = append(seeds, scanner.Text())
+	}
+	if scanner.Err() != nil {
+		return nil, scanner.Err()
+	}
+	return seeds, nil
+}
+
+// Divides seeds into parts of at most maxLines seeds.
+func splitSeeds(seeds []string, maxLines int, strategy SplitStrategy) [][]string {
+	if strategy == SplitHost {
+		return splitByHost(seeds, maxLines)
+	}
+	return chunkSeeds(seeds, maxLines)
+}
+
+func chunkSeeds(seeds []string, size int) [][]string {
+	var parts [][]string
+	for len(seeds) > 0 {
+		n := min(size, len(seeds))
+		parts = append(parts, seeds[:n])
+		seeds = seeds[n:]
+	}
+	return parts
+}
+
+// Groups seeds by registrable domain and packs the groups into parts with
+// first fit decreasing. Domain with more than maxLines seeds is cut into
+// full parts and only the rest of it is packed with other domains.
+func splitByHost(seeds []string, maxLines int) [][]string {
+	var groups [][]string
+	index := make(map[string]int)
+	for _, seed := range seeds {
+		domain := seedDomain(seed)
+		i, ok := index[domain]
+		if !ok {
+			i = len(groups)
+			index[domain] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], seed)
+	}
+
+	var parts [][]string
+	var rest [][]string
+	for _, group := range groups {
+		for len(group) >= maxLines {
+			parts = append(parts, group[:maxLines])
+			group = group[maxLines:]
+		}
+		if len(group) > 0 {
+			rest = append(rest, group)
+		}
+	}
+
+	// Stable sort keeps order of the first occurrence for equal sizes,
+	// so the same seeds give always the same parts.
+	slices.SortStableFunc(rest, func(a, b []string) int {
+		return len(b) - len(a)
+	})
+	packed := len(parts)
+	for _, group := range rest {
+		fitted := false
+		for i := packed; i < len(parts); i++ {
+			if len(parts[i])+len(group) <= maxLines {
+				parts[i] = append(parts[i], group...)
+				fitted = true
+				break
+			}
+		}
+		if !fitted {
+			parts = append(parts, slices.Clone(group))
+		}
+	}
+	return parts
+}
+
+// Returns registrable domain of the seed, e.g. example.co.uk for
+// www.example.co.uk. Host is used for IP addresses and hosts without
+// public suffix, the whole line for seeds that are not URLs.
+func seedDomain(seed string) string {
+	seed = strings.TrimSpace(seed)
+	if !strings.Contains(seed, "://") {
+		seed = "http://" + seed
+	}
+	parsed, err := url.Parse(seed)
+	if err != nil || parsed.Hostname() == "" {
+		return seed
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
+
+// Writes seeds of every crawl into its seeds file.
+func writeSeedFiles(crawls []*Crawl, parts [][]string) error {
+	for i, crawl := range crawls {
+		err := writeSeedFile(crawl.SeedsFile, parts[i])
+		if err != nil {
+			return fmt.Errorf("failed to write seeds file %s with error: %w", crawl.SeedsFile, err)
+		}
+	}
+	return nil
+}
+
+func writeSeedFile(path string, seeds []string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Heritrix may run as different user
+	err = file.Chmod(0666)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	for _, seed := range seeds {
+		_, err = writer.WriteString(seed)
+		if err != nil {
+			return err
+		}
+		err = writer.WriteByte('\n')
+		if err != nil {
+			return err
+		}
+	}
+	err = writer.Flush()
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
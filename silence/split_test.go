@@ -0,0 +1,50 @@
+package silence
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSeedDomain(t *testing.T) {
+	cases := map[string]string{
+		"https://www.example.com/a":     "example.com",
+		"http://news.Example.CO.UK:80/": "example.co.uk",
+		"example.org/page":              "example.org",
+		"http://127.0.0.1:8080/":        "127.0.0.1",
+	}
+	for seed, expected := range cases {
+		if domain := seedDomain(seed); domain != expected {
+			t.Errorf("domain of %s is %s, expected %s", seed, domain, expected)
+		}
+	}
+}
+
+func TestSplitByHost(t *testing.T) {
+	seeds := []string{
+		"https://a.com/1",
+		"https://b.com/1",
+		"https://www.a.com/2",
+		"https://c.com/1",
+		"https://big.com/1",
+		"https://big.com/2",
+		"https://big.com/3",
+		"https://big.com/4",
+		"https://b.com/2",
+	}
+
+	parts := splitByHost(seeds, 3)
+	expected := [][]string{
+		{"https://big.com/1", "https://big.com/2", "https://big.com/3"},
+		{"https://a.com/1", "https://www.a.com/2", "https://c.com/1"},
+		{"https://b.com/1", "https://b.com/2", "https://big.com/4"},
+	}
+	if !slices.EqualFunc(parts, expected, slices.Equal) {
+		t.Fatalf("got parts %v", parts)
+	}
+
+	for _, part := range splitSeeds(seeds, 3, SplitLines) {
+		if len(part) > 3 {
+			t.Fatalf("part %v is bigger than limit", part)
+		}
+	}
+}
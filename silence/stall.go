@@ -0,0 +1,70 @@
+package silence
+
+import (
+	"fmt"
+	"silence/heritrix"
+	"time"
+)
+
+// Ends crawl that makes no progress before MaxWaitSeconds expires.
+type StallConfig struct {
+	// Crawl is stalled when downloaded URIs did not change, or the frontier
+	// was empty while the crawl was running, for this long. 0 disables it.
+	TimeoutSeconds int
+	// Request Heritrix checkpoint before the stalled crawl is terminated
+	Checkpoint bool
+}
+
+func (sc *StallConfig) enabled() bool {
+	return sc != nil && sc.TimeoutSeconds > 0
+}
+
+func (sc *StallConfig) validate(v *validator) {
+	v.check(sc.TimeoutSeconds >= 0, "Stall.TimeoutSeconds must not be negative")
+}
+
+// Tracks progress of one crawl between status checks.
+type stallDetector struct {
+	timeout time.Duration
+
+	downloaded int64
+	// Last time downloaded URIs changed, zero when crawl is not running
+	progressAt time.Time
+	// Since when the frontier is empty, zero when it is not
+	emptySince time.Time
+}
+
+func newStallDetector(config *StallConfig) *stallDetector {
+	return &stallDetector{timeout: time.Duration(config.TimeoutSeconds) * time.Second}
+}
+
+// Returns reason when the crawl is stalled. Only running crawl with
+// reported totals is checked, any other state starts tracking again.
+func (sd *stallDetector) check(status *heritrix.Job, now time.Time) (string, bool) {
+	if status.ControllerState != heritrix.StateRunning || status.URITotals == nil {
+		sd.progressAt = time.Time{}
+		sd.emptySince = time.Time{}
+		return "", false
+	}
+
+	totals := status.URITotals
+	if sd.progressAt.IsZero() || totals.DownloadedURICount != sd.downloaded {
+		sd.downloaded = totals.DownloadedURICount
+		sd.progressAt = now
+	}
+	if totals.QueuedURICount > 0 {
+		sd.emptySince = time.Time{}
+	} else if sd.emptySince.IsZero() {
+		sd.emptySince = now
+	}
+
+	if stalled := now.Sub(sd.progressAt); stalled >= sd.timeout {
+		return fmt.Sprintf("no URI was downloaded for %s", stalled.Round(time.Second)), true
+	}
+	if !sd.emptySince.IsZero() {
+		if empty := now.Sub(sd.emptySince); empty >= sd.timeout {
+			return fmt.Sprintf("frontier is empty for %s but the crawl did not finish", empty.Round(time.Second)), true
+		}
+	}
+	return "", false
+}
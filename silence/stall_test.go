@@ -0,0 +1,56 @@
+package silence
+
+import (
+	"silence/heritrix"
+	"testing"
+	"time"
+)
+
+func runningStatus(downloaded, queued int64) *heritrix.Job {
+	return &heritrix.Job{
+		ControllerState: heritrix.StateRunning,
+		URITotals: &heritrix.URITotalsReport{
+			DownloadedURICount: downloaded,
+			QueuedURICount:     queued,
+		},
+	}
+}
+
+func TestStallDetector(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	minute := func(n int) time.Time { return start.Add(time.Duration(n) * time.Minute) }
+
+	detector := newStallDetector(&StallConfig{TimeoutSeconds: 600})
+	checks := []struct {
+		status  *heritrix.Job
+		at      time.Time
+		stalled bool
+	}{
+		{runningStatus(10, 50), minute(0), false},
+		{runningStatus(20, 50), minute(5), false},
+		{runningStatus(20, 50), minute(14), false},
+		// Paused crawl is not checked and tracking starts again
+		{&heritrix.Job{ControllerState: heritrix.StatePaused}, minute(20), false},
+		{runningStatus(20, 50), minute(21), false},
+		{runningStatus(20, 50), minute(31), true},
+	}
+	for i, check := range checks {
+		_, stalled := detector.check(check.status, check.at)
+		if stalled != check.stalled {
+			t.Fatalf("check %d: stalled is %v, expected %v", i, stalled, check.stalled)
+		}
+	}
+
+	detector = newStallDetector(&StallConfig{TimeoutSeconds: 600})
+	for i := 0; i < 10; i++ {
+		_, stalled := detector.check(runningStatus(int64(100+i), 0), minute(i))
+		if stalled {
+			t.Fatalf("crawl with empty frontier stalled after %d minutes", i)
+		}
+	}
+	reason, stalled := detector.check(runningStatus(110, 0), minute(10))
+	if !stalled {
+		t.Fatal("crawl with empty frontier for 10 minutes is not stalled")
+	}
+	t.Log(reason)
+}
@@ -0,0 +1,128 @@
+package silence
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// File in working directory holding the queue of crawls, so interrupted
+// job can be resumed.
+const StateFileName = "silence-state.json"
+
+type CrawlStatus string
+
+const (
+	CrawlQueued   CrawlStatus = "queued"
+	CrawlRunning  CrawlStatus = "running"
+	CrawlFinished CrawlStatus = "finished"
+	CrawlFailed   CrawlStatus = "failed"
+)
+
+// Serialized queue of crawls
+type JobState struct {
+	Job     string
+	Updated time.Time
+	Crawls  []*Crawl
+}
+
+// Writes state of all crawls to StateFileName. The file is replaced
+// atomically, so crash during write never leaves half written state.
+func (job *Job) saveState() error {
+	state := JobState{
+		Job:     job.Name,
+		Updated: time.Now(),
+		Crawls:  job.crawls,
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(StateFileName, data, 0644)
+}
+
+func (job *Job) loadState() (*JobState, error) {
+	data, err := os.ReadFile(StateFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	state := new(JobState)
+	err = json.Unmarshal(data, state)
+	if err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+func (job *Job) removeState() error {
+	err := os.Remove(StateFileName)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Changes status of the crawl and persists the state.
+func (job *Job) setStatus(app *App, crawl *Crawl, status CrawlStatus) {
+	crawl.Status = status
+	err := job.saveState()
+	if err != nil {
+		// Not fatal, only resume will not be possible
+		app.Log.Error(
+			fmt.Sprintf("failed to save state to %s", StateFileName),
+			slog.Int("id", crawl.ID),
+			slog.String(StatusKey, string(status)),
+			slog.String(ErrorKey, err.Error()),
+		)
+	}
+}
+
+func stateExists() (bool, error) {
+	_, err := os.Stat(StateFileName)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return false, err
+}
+
+func writeFileAtomic(name string, data []byte, perm fs.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Chmod(perm)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Sync()
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
@@ -0,0 +1,136 @@
+package silence
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Exit status when Heritrix job could not be torn down and operator
+// must check Heritrix before the next run.
+const TeardownTimeoutStatus = 3
+
+// Watchdog of teardown. Heritrix closes WARCs and logs during teardown,
+// which can take long for big crawls.
+type TeardownConfig struct {
+	TimeoutSeconds int
+	IntervalMillis int
+	// Restart Heritrix started by silence when teardown times out
+	RestartHeritrix bool
+}
+
+func DefaultTeardownConfig() *TeardownConfig {
+	return &TeardownConfig{
+		TimeoutSeconds: 2 * 60 * 60,
+		IntervalMillis: 10_000,
+	}
+}
+
+func (tc *TeardownConfig) validate(v *validator) {
+	v.check(tc.TimeoutSeconds > 0, "Teardown.TimeoutSeconds must be bigger than 0")
+	v.check(tc.IntervalMillis > 0, "Teardown.IntervalMillis must be bigger than 0")
+}
+
+// Error returned when teardown did not finish in time and Heritrix was
+// not restarted.
+type TeardownTimeoutError struct {
+	Job     string
+	Timeout time.Duration
+	// Controller state or status description of the last check
+	State string
+}
+
+func (err *TeardownTimeoutError) Error() string {
+	return fmt.Sprintf("job %s was not torn down in %s, it is stuck in %s and must be shut down by operator",
+		err.Job, err.Timeout, err.State)
+}
+
+// Interval of progress logs while waiting for teardown
+const teardownLogInterval = time.Minute
+
+// Polls the job until it is torn down. On timeout Heritrix is restarted
+// when configured and possible, otherwise *TeardownTimeoutError is returned.
+func (crawl *Crawl) awaitTeardown(ctx context.Context, app *App) error {
+	config := crawl.Job.Teardown
+	timeout := time.Duration(config.TimeoutSeconds) * time.Second
+	interval := time.Duration(config.IntervalMillis) * time.Millisecond
+
+	started := time.Now()
+	deadline := started.Add(timeout)
+	lastLog := started
+	for {
+		status, err := crawl.client().Job(ctx, crawl.jobName())
+		if err != nil {
+			return err
+		}
+		crawl.observe(status)
+
+		if !status.IsRunning && status.IsLaunchable {
+			return nil
+		}
+
+		state := status.ControllerState
+		if state == "" {
+			state = status.StatusDescription
+		}
+		now := time.Now()
+		if now.After(deadline) {
+			return crawl.escalateTeardown(ctx, app, &TeardownTimeoutError{
+				Job:     crawl.jobName(),
+				Timeout: timeout,
+				State:   state,
+			})
+		}
+		if now.Sub(lastLog) >= teardownLogInterval {
+			lastLog = now
+			app.Log.Info(
+				"waiting for teardown",
+				slog.String("state", state),
+				slog.Duration("elapsed", now.Sub(started).Round(time.Second)),
+				slog.Duration("remaining", deadline.Sub(now).Round(time.Second)),
+			)
+		}
+
+		err = sleep(ctx, interval)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Restarts Heritrix when teardown timed out, the restart discards the
+// stuck job. Returns timeoutErr when restart is not possible or fails.
+func (crawl *Crawl) escalateTeardown(ctx context.Context, app *App, timeoutErr *TeardownTimeoutError) error {
+	app.Log.Error(
+		"teardown timed out",
+		slog.String(ErrorKey, timeoutErr.Error()),
+	)
+
+	if !crawl.Job.Teardown.RestartHeritrix {
+		return timeoutErr
+	}
+	if crawl.Job.supervisor == nil {
+		app.Log.Warn("heritrix was not started by silence, it cannot be restarted")
+		return timeoutErr
+	}
+
+	// Restart would kill other crawls running on the instance
+	others, ok := crawl.slot.endpoint.beginRestart()
+	if !ok {
+		app.Log.Warn(fmt.Sprintf("%d other crawls run on heritrix, it is not restarted", others))
+		return timeoutErr
+	}
+	defer crawl.slot.endpoint.endRestart()
+
+	app.Log.Warn("restarting heritrix to get rid of stuck job")
+	err := crawl.Job.supervisor.restart(ctx, app)
+	if err != nil {
+		app.Log.Error(
+			"restart of heritrix failed",
+			slog.String(ErrorKey, err.Error()),
+		)
+		return timeoutErr
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package silence
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+	"text/template"
+)
+
+// Spring beans of crawler-beans that silence and its overrides rely on.
+var requiredBeans = []string{"seeds", "warcWriter", "crawlLimiter", "metadata"}
+
+// Parses crawler-beans template. Template is executed with JobConfig,
+// unknown field is an error of execution, so validateTemplate finds it by
+// rendering the sample config.
+func parseTemplate(path string) (*template.Template, error) {
+	return template.New(filepath.Base(path)).ParseFiles(path)
+}
+
+// Returns the template parsed once for all crawls.
+func (job *Job) template() (*template.Template, error) {
+	job.templateOnce.Do(func() {
+		job.beansTemplate, job.templateErr = parseTemplate(job.TemplatePath)
+	})
+	return job.beansTemplate, job.templateErr
+}
+
+// Config with values of the first crawl, used to render the template
+// before any crawl exists.
+func (job *Job) sampleConfig() *JobConfig {
+	config := job.Config.Copy()
+	config.seedsFile = filepath.Join(SeedsDirectory, "seeds-sample-000.txt")
+	config.crawlType = job.Name
+	return config
+}
+
+// Renders the template with sample config and checks the output is well
+// formed XML with all required beans. Values are single line, so line
+// numbers of the output match lines of the template.
+func (job *Job) validateTemplate(v *validator) {
+	beansTemplate, err := job.template()
+	if err != nil {
+		// Error of text/template starts with name and line of the template
+		v.check(false, "TemplatePath cannot be parsed: %s", err)
+		return
+	}
+
+	var rendered bytes.Buffer
+	err = beansTemplate.Execute(&rendered, job.sampleConfig())
+	if err != nil {
+		v.check(false, "TemplatePath cannot be rendered: %s", err)
+		return
+	}
+
+	beans, err := beanLines(rendered.Bytes())
+	if err != nil {
+		var syntaxErr *xml.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			v.check(false, "TemplatePath is not well formed XML on line %d: %s", syntaxErr.Line, syntaxErr.Msg)
+		} else {
+			v.check(false, "TemplatePath is not well formed XML: %s", err)
+		}
+		return
+	}
+
+	for _, id := range requiredBeans {
+		v.check(len(beans[id]) > 0, "TemplatePath has no bean with id %q", id)
+	}
+	for _, id := range slices.Sorted(maps.Keys(beans)) {
+		lines := beans[id]
+		v.check(len(lines) < 2, "TemplatePath defines bean %q more than once, on lines %v", id, lines)
+	}
+}
+
+// Returns lines of all beans with id, beans in comments are skipped.
+func beanLines(data []byte) (map[string][]int, error) {
+	beans := make(map[string][]int)
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		// Position before the token is where its start tag begins
+		line, _ := decoder.InputPos()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "bean" {
+			continue
+		}
+		for _, attr := range start.Attr {
+			if attr.Name.Local == "id" {
+				beans[attr.Value] = append(beans[attr.Value], line)
+			}
+		}
+	}
+	return beans, nil
+}
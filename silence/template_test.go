@@ -0,0 +1,56 @@
+package silence
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func templateProblems(job *Job) []string {
+	v := new(validator)
+	job.validateTemplate(v)
+	return v.problems
+}
+
+func TestValidateRepositoryTemplate(t *testing.T) {
+	path, err := filepath.Abs(filepath.Join("..", "crawler-beans.template"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := DefaultJob(DefaultJobConfigPath)
+	job.Name = testJobName
+	job.TemplatePath = path
+	problems := templateProblems(job)
+	if len(problems) != 0 {
+		t.Fatalf("template of the repository has problems: %v", problems)
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		problem  string
+	}{
+		{"unknown field", strings.Replace(testTemplate, "{{.SeedsFile}}", "{{.Seeds}}", 1), `TemplatePath cannot be rendered: template: crawler-beans.template:7:8:`},
+		{"malformed xml", strings.Replace(testTemplate, `<bean id="seeds"/>`, `<bean id="seeds">`, 1), "TemplatePath is not well formed XML on line 15"},
+		{"missing bean", strings.Replace(testTemplate, `<bean id="crawlLimiter"/>`, "", 1), `TemplatePath has no bean with id "crawlLimiter"`},
+		{"duplicate bean", strings.Replace(testTemplate, `<bean id="metadata"/>`, "<bean id=\"seeds\"/>\n <bean id=\"metadata\"/>", 1), `TemplatePath defines bean "seeds" more than once, on lines [11 13]`},
+		{"commented bean", strings.Replace(testTemplate, `<bean id="warcWriter"/>`, `<!-- <bean id="warcWriter"/> -->`, 1), `TemplatePath has no bean with id "warcWriter"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			chdirTemp(t)
+			writeTestFile(t, "crawler-beans.template", test.template)
+			job := DefaultJob(DefaultJobConfigPath)
+			job.Name = testJobName
+
+			problems := templateProblems(job)
+			if len(problems) != 1 || !strings.HasPrefix(problems[0], test.problem) {
+				t.Fatalf("expected problem %q, got %v", test.problem, problems)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package silence
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Names of Heritrix jobs are used as directory names and in URLs.
+var safeNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Error listing all problems found by Validate.
+type ValidationError struct {
+	Problems []string
+}
+
+func (err *ValidationError) Error() string {
+	return fmt.Sprintf("job config has %d problems: %s", len(err.Problems), strings.Join(err.Problems, "; "))
+}
+
+type validator struct {
+	problems []string
+}
+
+func (v *validator) check(ok bool, format string, args ...any) {
+	if !ok {
+		v.problems = append(v.problems, fmt.Sprintf(format, args...))
+	}
+}
+
+// Checks that file is readable.
+func (v *validator) readable(field string, path string) {
+	if path == "" {
+		v.problems = append(v.problems, fmt.Sprintf("%s must be set", field))
+		return
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		v.problems = append(v.problems, fmt.Sprintf("%s is not readable: %s", field, err))
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		v.problems = append(v.problems, fmt.Sprintf("%s is not readable: %s", field, err))
+		return
+	}
+	v.check(info.Mode().IsRegular(), "%s (%s) is not a regular file", field, path)
+}
+
+// Checks the value can be safely used inside crawler-beans property.
+func (v *validator) singleLine(field string, value string) {
+	v.check(!strings.ContainsAny(value, "\r\n"), "%s must not contain new line", field)
+}
+
+// Checks all fields of the job and returns *ValidationError with all
+// problems found. It does not contact Heritrix.
+func (job *Job) Validate() error {
+	v := new(validator)
+
+	v.check(job.Name != "", "Name must be set")
+	v.check(job.Name == "" || safeNamePattern.MatchString(job.Name),
+		"Name %q is not safe for Heritrix job directory, use only letters, digits, '.', '_' and '-'", job.Name)
+
+	job.validateCrawler(v)
+
+	problems := len(v.problems)
+	v.check(job.TemplatePath != CrawlerBeansName, "TemplatePath cannot be named %s", CrawlerBeansName)
+	v.readable("TemplatePath", job.TemplatePath)
+	if len(v.problems) == problems && job.Config != nil {
+		job.validateTemplate(v)
+	}
+	v.readable("SeedsPath", job.SeedsPath)
+
+	v.check(job.MaxLines > 0, "MaxLines must be bigger than 0")
+	v.check(job.MaxIterations > 0, "MaxIterations must be bigger than 0")
+	v.check(job.MaxWaitSeconds > 0, "MaxWaitSeconds must be bigger than 0")
+	v.check(job.SplitStrategy.valid(), "SplitStrategy %q is not known, use %s or %s", job.SplitStrategy, SplitLines, SplitHost)
+
+	if job.Config == nil {
+		v.check(false, "Config must be set")
+	} else {
+		job.Config.validate(v)
+	}
+
+	if job.Stall != nil {
+		job.Stall.validate(v)
+	}
+
+	if job.Retry == nil {
+		v.check(false, "Retry must be set")
+	} else {
+		job.Retry.validate(v)
+	}
+
+	if job.Wait == nil {
+		v.check(false, "Wait must be set")
+	} else {
+		job.Wait.validate(v)
+	}
+
+	if job.Teardown == nil {
+		v.check(false, "Teardown must be set")
+	} else {
+		job.Teardown.validate(v)
+	}
+
+	if job.PartJobs == nil {
+		v.check(false, "PartJobs must be set")
+	} else {
+		job.PartJobs.validate(v)
+	}
+
+	if job.Heritrix.managed() {
+		job.Heritrix.validate(v)
+	}
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+func (job *Job) validateCrawler(v *validator) {
+	if len(job.Crawlers) > 0 {
+		for i, endpoint := range job.Crawlers {
+			endpoint.validate(v, i)
+		}
+		v.check(len(job.Crawlers) == 1 || !job.Heritrix.managed(),
+			"Heritrix.Home can be used only with single crawler")
+		return
+	}
+
+	validateAddress(v, "CrawlerAddress", job.CrawlerAddress)
+	v.check(job.CrawlerUsername != "", "CrawlerUsername must be set")
+	v.check(job.CrawlerPassword != "", "CrawlerPassword must be set")
+}
+
+func validateAddress(v *validator, field string, address string) {
+	original := address
+	if !strings.HasPrefix(address, "http://") && !strings.HasPrefix(address, "https://") {
+		address = "https://" + address
+	}
+	parsed, err := url.Parse(address)
+	if err != nil {
+		v.check(false, "%s is not valid: %s", field, err)
+	} else {
+		v.check(parsed.Hostname() != "", "%s %q has no host", field, original)
+	}
+}
+
+func (jc *JobConfig) validate(v *validator) {
+	v.singleLine("Config.Operator", jc.Operator)
+	v.check(jc.Operator != "", "Config.Operator must be set")
+	v.singleLine("Config.Description", jc.Description)
+	v.singleLine("Config.DedupDir", jc.DedupDir)
+	v.check(jc.DedupDir != "", "Config.DedupDir must be set")
+	v.check(jc.DataLimit >= 0, "Config.DataLimit must not be negative")
+	v.check(jc.TimeLimit >= 0, "Config.TimeLimit must not be negative")
+	v.check(jc.ToeThreads > 0, "Config.ToeThreads must be bigger than 0")
+	v.check(jc.MaxHops >= 0, "Config.MaxHops must not be negative")
+	// Suffix is part of crawl name, which is used for WARC names and directories
+	v.check(jc.CrawlNameSuffix == "" || safeNamePattern.MatchString(jc.CrawlNameSuffix),
+		"Config.CrawlNameSuffix %q may contain only letters, digits, '.', '_' and '-'", jc.CrawlNameSuffix)
+}
+
+func (hc *HeritrixConfig) validate(v *validator) {
+	info, err := os.Stat(hc.Home)
+	if err != nil {
+		v.check(false, "Heritrix.Home is not accessible: %s", err)
+	} else {
+		v.check(info.IsDir(), "Heritrix.Home (%s) is not a directory", hc.Home)
+	}
+	v.check(hc.StartupTimeoutSeconds > 0, "Heritrix.StartupTimeoutSeconds must be bigger than 0")
+	v.check(hc.ShutdownTimeoutSeconds > 0, "Heritrix.ShutdownTimeoutSeconds must be bigger than 0")
+	v.check(hc.LogFile != "", "Heritrix.LogFile must be set")
+}
+
+// Logs every problem of validation error.
+func logValidation(app *App, err error) {
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		app.Log.Error("validation failed", slog.String(ErrorKey, err.Error()))
+		return
+	}
+	for _, problem := range validationErr.Problems {
+		app.Log.Error("invalid job config", slog.String("problem", problem))
+	}
+}
@@ -0,0 +1,64 @@
+package silence
+
+import (
+	"errors"
+	"silence/heritrix/heritrixtest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValidateTestJob(t *testing.T) {
+	server := heritrixtest.NewServer("admin", "secret")
+	defer server.Close()
+
+	job := newTestJob(t, server, 1)
+	err := job.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	chdirTemp(t)
+
+	job := DefaultJob(DefaultJobConfigPath)
+	job.Name = "../topics"
+	job.CrawlerAddress = "http://"
+	job.TemplatePath = CrawlerBeansName
+	job.MaxLines = 0
+	job.Config.Operator = "line\nbreak"
+	job.Config.ToeThreads = 10
+	job.Config.DedupDir = "dedup"
+
+	err := job.Validate()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+
+	expected := []string{
+		"Name",
+		"CrawlerAddress",
+		"CrawlerUsername",
+		"CrawlerPassword",
+		"TemplatePath cannot",
+		"TemplatePath is not readable",
+		"SeedsPath",
+		"MaxLines",
+		"MaxWaitSeconds",
+		"Config.Operator",
+	}
+	var prefixes []string
+	for _, problem := range validationErr.Problems {
+		for _, prefix := range expected {
+			if strings.HasPrefix(problem, prefix) {
+				prefixes = append(prefixes, prefix)
+				break
+			}
+		}
+	}
+	if !slices.Equal(prefixes, expected) || len(validationErr.Problems) != len(expected) {
+		t.Fatalf("unexpected problems:\n%s", strings.Join(validationErr.Problems, "\n"))
+	}
+}
@@ -0,0 +1,136 @@
+package silence
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"silence/heritrix"
+	"time"
+)
+
+// How long to wait for Heritrix to reach state expected after an action.
+type WaitConfig struct {
+	// Delay between status checks
+	IntervalMillis int
+	// Default timeout of each action
+	TimeoutSeconds int
+	// Timeouts of individual actions, e.g. launch with many seeds takes long
+	Timeouts map[string]int
+}
+
+func DefaultWaitConfig() *WaitConfig {
+	return &WaitConfig{
+		IntervalMillis: 1000,
+		TimeoutSeconds: 300,
+		Timeouts:       map[string]int{"launch": 1800},
+	}
+}
+
+func (wc *WaitConfig) validate(v *validator) {
+	v.check(wc.IntervalMillis > 0, "Wait.IntervalMillis must be bigger than 0")
+	v.check(wc.TimeoutSeconds > 0, "Wait.TimeoutSeconds must be bigger than 0")
+	for action, timeout := range wc.Timeouts {
+		_, known := actionStates[action]
+		v.check(known, "Wait.Timeouts has unknown action %q", action)
+		v.check(timeout > 0, "Wait.Timeouts of %s must be bigger than 0", action)
+	}
+}
+
+func (wc *WaitConfig) interval() time.Duration {
+	return time.Duration(wc.IntervalMillis) * time.Millisecond
+}
+
+func (wc *WaitConfig) timeout(action string) time.Duration {
+	seconds, ok := wc.Timeouts[action]
+	if !ok {
+		seconds = wc.TimeoutSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// State of Heritrix job expected after an action.
+type expectedState struct {
+	description string
+	reached     func(status *heritrix.Job) bool
+}
+
+// States expected after actions, action missing here is not awaited.
+// Teardown to not running may take hours, it is awaited by awaitTeardown
+// with its own timeout.
+var actionStates = map[string]expectedState{
+	// Unbuilt job is launchable as well, only built one has controller
+	// state and can be torn down
+	"build": {"built", func(status *heritrix.Job) bool {
+		return status.ControllerState != "" || status.HasAction("teardown")
+	}},
+	"launch": {"PAUSED or RUNNING", func(status *heritrix.Job) bool {
+		return status.ControllerState == heritrix.StatePaused || pastRunning(status)
+	}},
+	// Small crawl may finish before the first check
+	"unpause": {"RUNNING", pastRunning},
+	"terminate": {"FINISHED", func(status *heritrix.Job) bool {
+		return status.ControllerState == heritrix.StateFinished
+	}},
+}
+
+func pastRunning(status *heritrix.Job) bool {
+	switch status.ControllerState {
+	case heritrix.StateRunning, heritrix.StateStopping, heritrix.StateFinished:
+		return true
+	}
+	return false
+}
+
+// Error returned when Heritrix did not reach expected state in time.
+type StuckError struct {
+	Action   string
+	Expected string
+	// Controller state or status description of the last check
+	State   string
+	Timeout time.Duration
+}
+
+func (err *StuckError) Error() string {
+	return fmt.Sprintf("heritrix job did not become %s after %s in %s, it is stuck in %s",
+		err.Expected, err.Action, err.Timeout, err.State)
+}
+
+// Polls the job until it reaches state expected after action.
+func (crawl *Crawl) waitFor(ctx context.Context, app *App, action string) error {
+	expected, ok := actionStates[action]
+	if !ok {
+		return nil
+	}
+
+	config := crawl.Job.Wait
+	timeout := config.timeout(action)
+	deadline := time.Now().Add(timeout)
+	for {
+		status, err := crawl.client().Job(ctx, crawl.jobName())
+		if err != nil {
+			return err
+		}
+		crawl.observe(status)
+
+		if expected.reached(status) {
+			return nil
+		}
+
+		state := status.ControllerState
+		if state == "" {
+			state = status.StatusDescription
+		}
+		if time.Now().After(deadline) {
+			return &StuckError{Action: action, Expected: expected.description, State: state, Timeout: timeout}
+		}
+		app.Log.Debug(
+			fmt.Sprintf("waiting for %s after %s", expected.description, action),
+			slog.String("state", state),
+		)
+
+		err = sleep(ctx, config.interval())
+		if err != nil {
+			return err
+		}
+	}
+}
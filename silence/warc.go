@@ -0,0 +1,122 @@
+package silence
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Property override in crawler-beans with directories for WARCs
+const warcStorePathsKey = "warcWriter.storePaths"
+
+const openSuffix = ".open"
+
+const openWarcPattern = "*.warc.gz" + openSuffix
+
+// Returns WARC store paths found in rendered crawler-beans. Heritrix
+// accepts comma separated list of paths in the property override.
+func parseWarcStorePaths(crawlerBeans []byte) []string {
+	var paths []string
+	scanner := bufio.NewScanner(bytes.NewReader(crawlerBeans))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		value, ok := strings.CutPrefix(line, warcStorePathsKey+"=")
+		if !ok {
+			continue
+		}
+		for _, path := range strings.Split(value, ",") {
+			path = strings.TrimSpace(path)
+			if path != "" {
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths
+}
+
+// Files touched by recovery of open WARCs
+type warcRecovery struct {
+	Recovered []string
+	Untouched []string
+}
+
+// Strips .open suffix from WARCs in dir left by terminated crawl. Only WARCs
+// whose gzip members can be fully read are renamed, the rest is left for operator.
+func recoverOpenWarcs(app *App, dir string) (*warcRecovery, error) {
+	recovery := new(warcRecovery)
+
+	matches, err := filepath.Glob(filepath.Join(dir, openWarcPattern))
+	if err != nil {
+		return recovery, err
+	}
+
+	for _, openName := range matches {
+		finalName := strings.TrimSuffix(openName, openSuffix)
+
+		reason, err := checkWarc(openName, finalName)
+		if err != nil {
+			return recovery, err
+		}
+		if reason != "" {
+			recovery.Untouched = append(recovery.Untouched, openName)
+			app.Log.Warn(
+				"open warc left untouched",
+				slog.String("file", openName),
+				slog.String("reason", reason),
+			)
+			continue
+		}
+
+		err = os.Rename(openName, finalName)
+		if err != nil {
+			return recovery, err
+		}
+		recovery.Recovered = append(recovery.Recovered, finalName)
+		app.Log.Info(
+			"open warc recovered",
+			slog.String("file", finalName),
+		)
+	}
+
+	return recovery, nil
+}
+
+// Returns reason why the WARC must not be renamed, or empty string when it is fine.
+// Error is returned only when the check itself failed.
+func checkWarc(openName, finalName string) (string, error) {
+	_, err := os.Stat(finalName)
+	if err == nil {
+		return fmt.Sprintf("%s already exists", filepath.Base(finalName)), nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+
+	file, err := os.Open(openName)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(bufio.NewReader(file))
+	if err != nil {
+		return fmt.Sprintf("not readable gzip: %s", err), nil
+	}
+	defer reader.Close()
+
+	// Reader is in multistream mode, so every member is checked
+	_, err = io.Copy(io.Discard, reader)
+	if err != nil {
+		return fmt.Sprintf("corrupted gzip member: %s", err), nil
+	}
+
+	return "", nil
+}
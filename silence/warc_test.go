@@ -0,0 +1,77 @@
+package silence
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func gzipMembers(t *testing.T, members ...string) []byte {
+	var buf bytes.Buffer
+	for _, member := range members {
+		writer := gzip.NewWriter(&buf)
+		_, err := writer.Write([]byte(member))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = writer.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestParseWarcStorePaths(t *testing.T) {
+	beans := []byte("warcWriter.prefix=x\n  warcWriter.storePaths=/a/b, /c \nother=1\n")
+	paths := parseWarcStorePaths(beans)
+	if !slices.Equal(paths, []string{"/a/b", "/c"}) {
+		t.Fatalf("got %v", paths)
+	}
+}
+
+func TestRecoverOpenWarcs(t *testing.T) {
+	dir := t.TempDir()
+	complete := gzipMembers(t, "WARC/1.0 first", "WARC/1.0 second")
+	files := map[string][]byte{
+		"ok.warc.gz.open":        complete,
+		"truncated.warc.gz.open": complete[:len(complete)-10],
+		"exists.warc.gz.open":    complete,
+		"exists.warc.gz":         complete,
+		"other.warc.gz":          complete,
+	}
+	for name, data := range files {
+		err := os.WriteFile(filepath.Join(dir, name), data, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recovery, err := recoverOpenWarcs(newTestApp(t), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(recovery.Recovered, []string{filepath.Join(dir, "ok.warc.gz")}) {
+		t.Errorf("recovered %v", recovery.Recovered)
+	}
+	untouched := []string{
+		filepath.Join(dir, "exists.warc.gz.open"),
+		filepath.Join(dir, "truncated.warc.gz.open"),
+	}
+	if !slices.Equal(recovery.Untouched, untouched) {
+		t.Errorf("untouched %v", recovery.Untouched)
+	}
+
+	_, err = os.Stat(filepath.Join(dir, "ok.warc.gz.open"))
+	if err == nil {
+		t.Error("open warc was not renamed")
+	}
+	_, err = os.Stat(filepath.Join(dir, "truncated.warc.gz.open"))
+	if err != nil {
+		t.Errorf("truncated warc must stay open: %v", err)
+	}
+}